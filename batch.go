@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Orders for displaying several references.
+const (
+	sortInput     = "input"     // As given
+	sortCanonical = "canonical" // By book, chapter and verse
+)
+
+// utf8BOM is the byte order mark some Windows editors put at the start of
+// UTF-8 files.
+const utf8BOM = "\ufeff"
+
+// readReferenceFile reads one reference per line from path, or from stdin
+// when path is "-". Blank lines and lines starting with '#' are skipped, as
+// is a leading byte order mark.
+func readReferenceFile(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var references []string
+	scanner := bufio.NewScanner(r)
+	for first := true; scanner.Scan(); first = false {
+		line := scanner.Text()
+		if first {
+			line = strings.TrimPrefix(line, utf8BOM)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		references = append(references, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return references, nil
+}
+
+// sortReferences orders references canonically. References that can't be
+// parsed keep their relative order after all the others.
+func sortReferences(references []string) {
+	sort.SliceStable(references, func(i, j int) bool {
+		a, aok := parseReference(references[i])
+		b, bok := parseReference(references[j])
+		if aok != bok {
+			return aok
+		}
+		return aok && a.Less(b)
+	})
+}
+
+// dedupeReferences drops references that normalize to one seen earlier in
+// the list, returning the remaining references and how many were dropped.
+func dedupeReferences(references []string) ([]string, int) {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, reference := range references {
+		key := normalizeReference(reference)
+		if seen[key] {
+			debugLog.Printf("skipping duplicate reference %q", reference)
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, reference)
+	}
+	return unique, len(references) - len(unique)
+}
+
+// runBatch fetches up to workers references at a time and displays them in
+// order, continuing past failures. It returns how many passages it showed
+// and reports whether every reference was fetched and displayed.
+func runBatch(client *Dispatcher, references []string, workers int, opts DisplayOptions) (shown int, ok bool) {
+	type result struct {
+		verse *ESVResponse
+		err   error
+	}
+	results := make([]chan result, len(references))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+
+	next := make(chan int)
+	go func() {
+		for i := range references {
+			next <- i
+		}
+		close(next)
+	}()
+	for w := 0; w < min(workers, len(references)); w++ {
+		go func() {
+			for i := range next {
+				verse, err := client.FetchVerse(references[i])
+				results[i] <- result{verse, err}
+			}
+		}()
+	}
+
+	ok = true
+	for i, reference := range references {
+		announceFetch(reference, opts)
+		r := <-results[i]
+		if r.err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", reference, r.err)
+			ok = false
+			continue
+		}
+		if err := displayVerse(r.verse, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", reference, err)
+			ok = false
+			continue
+		}
+		shown += len(r.verse.Passages)
+	}
+	return shown, ok
+}
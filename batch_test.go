@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRunBatchRawResponse(t *testing.T) {
+	references := []string{"John 3:16", "Romans 8:28", "Psalm 23:1", "Genesis 1:1", "John 11:35"}
+	rawOutput = &pendingOutput{}
+	t.Cleanup(func() { rawOutput = nil })
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		fmt.Fprintf(w, `{"canonical": %q, "passages": ["Text of %s"]}`, q, q)
+	}, FetchOptions{RawResponse: rawOutput})
+	dispatcher := NewDispatcher(map[string]Provider{"ESV": client}, Config{}, "", nil)
+
+	var shown int
+	var ok bool
+	got := captureOutput(t, func() {
+		shown, ok = runBatch(dispatcher, references, 3, DisplayOptions{Format: formatPlain})
+	})
+	if !ok || shown != len(references) {
+		t.Fatalf("runBatch = %d, %v, want %d, true", shown, ok, len(references))
+	}
+	// Each raw body is whole and comes ahead of its passage; the passages
+	// keep the order of the references
+	last := -1
+	for _, reference := range references {
+		raw := strings.Index(got, fmt.Sprintf(`{"canonical": %q, "passages": ["Text of %s"]}`, reference, reference))
+		passage := strings.Index(got, "\n\nText of "+reference+"\n")
+		if raw < 0 || passage < 0 || raw > passage || passage < last {
+			t.Fatalf("%s: raw body at %d, passage at %d after %d:\n%s", reference, raw, passage, last, got)
+		}
+		last = passage
+	}
+}
+
+func TestReadReferenceFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"plain", "John 3:16\nRomans 8:28\n", []string{"John 3:16", "Romans 8:28"}},
+		{"byte order mark", utf8BOM + "John 3:16\nRomans 8:28\n", []string{"John 3:16", "Romans 8:28"}},
+		{"byte order mark and CRLF", utf8BOM + "John 3:16\r\nRomans 8:28\r\n", []string{"John 3:16", "Romans 8:28"}},
+		{"byte order mark before a comment", utf8BOM + "# Sunday\nJohn 3:16\n", []string{"John 3:16"}},
+		{"blank lines and comments", "\n# Sunday\n  John 3:16  \n\n", []string{"John 3:16"}},
+		{"only the first mark", "John 3:16\n" + utf8BOM + "Romans 8:28\n", []string{"John 3:16", utf8BOM + "Romans 8:28"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "verses.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readReferenceFile(path)
+			if err != nil {
+				t.Fatalf("readReferenceFile: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("readReferenceFile = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunBatchCountsShown(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch q := r.URL.Query().Get("q"); q {
+		case "Nothing 1:1":
+			fmt.Fprint(w, `{"canonical": "", "passages": []}`)
+		case "Broken 1:1":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			fmt.Fprintf(w, `{"canonical": %q, "passages": ["Text"]}`, q)
+		}
+	}, FetchOptions{})
+	dispatcher := NewDispatcher(map[string]Provider{"ESV": client}, Config{}, "", nil)
+
+	tests := []struct {
+		name       string
+		references []string
+		wantShown  int
+		wantOK     bool
+	}{
+		{"all shown", []string{"John 3:16", "Romans 8:28"}, 2, true},
+		{"failures", []string{"Broken 1:1", "Broken 1:1"}, 0, false},
+		{"nothing found", []string{"Nothing 1:1"}, 0, true},
+		{"some failed", []string{"John 3:16", "Broken 1:1"}, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var shown int
+			var ok bool
+			captureOutput(t, func() {
+				shown, ok = runBatch(dispatcher, tt.references, 2, DisplayOptions{Format: formatPlain})
+			})
+			if shown != tt.wantShown || ok != tt.wantOK {
+				t.Errorf("runBatch = %d, %v, want %d, %v", shown, ok, tt.wantShown, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// bibleAPIBaseURL is the endpoint of bible-api.com, which serves public
+// domain translations without an API key.
+const bibleAPIBaseURL = "https://bible-api.com/"
+
+// bibleAPITranslations lists the translations fetched from bible-api.com.
+var bibleAPITranslations = []string{"ASV", "BBE", "DARBY", "KJV", "WEB", "YLT"}
+
+// bibleAPIResponse is the part of a bible-api.com response that is used.
+type bibleAPIResponse struct {
+	Reference string `json:"reference"`
+	Verses    []struct {
+		BookName string `json:"book_name"`
+		Chapter  int    `json:"chapter"`
+		Verse    int    `json:"verse"`
+		Text     string `json:"text"`
+	} `json:"verses"`
+}
+
+// bibleAPIProvider fetches one translation from bible-api.com. Requests go
+// through a BibleClient for its caching, retries and rate limiting.
+type bibleAPIProvider struct {
+	client      *BibleClient
+	baseURL     string
+	translation string
+}
+
+func newBibleAPIProvider(client *BibleClient, translation string) *bibleAPIProvider {
+	return &bibleAPIProvider{client: client, baseURL: bibleAPIBaseURL, translation: translation}
+}
+
+// FetchVerse fetches each ";"-separated reference in query as a passage of
+// its own, as bible-api.com has no notion of several references at once.
+func (p *bibleAPIProvider) FetchVerse(query string) (*ESVResponse, error) {
+	if p.client.options.HTML {
+		return nil, errors.New("bible-api.com doesn't support -with-html")
+	}
+	if err := validateReference(query, p.client.options.MaxSpanVerses); err != nil {
+		return nil, err
+	}
+
+	resp := &ESVResponse{Query: query}
+	var canonical []string
+	for _, reference := range strings.Split(query, ";") {
+		reference = strings.TrimSpace(reference)
+		var passage bibleAPIResponse
+		params := url.Values{"translation": {strings.ToLower(p.translation)}}
+		info, err := p.client.fetchJSON(p.baseURL+url.PathEscape(reference), params, &passage)
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return &ESVResponse{Query: query}, nil // Unknown references are a 404
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(passage.Verses) == 0 {
+			return &ESVResponse{Query: query}, nil
+		}
+		resp.Stale = resp.Stale || info.Stale
+		// The passages are as old as the oldest of them
+		if fetched := info.Fetched.Truncate(time.Second); resp.Fetched.IsZero() || fetched.Before(resp.Fetched) {
+			resp.Fetched = fetched
+		}
+
+		var text []string
+		var ids []int
+		for _, verse := range passage.Verses {
+			verseText := strings.Join(strings.Fields(verse.Text), " ")
+			if p.client.options.VerseNumbers {
+				verseText = fmt.Sprintf("[%d] %s", verse.Verse, verseText)
+			}
+			text = append(text, verseText)
+			if book, ok := lookupBook(verse.BookName); ok {
+				ids = append(ids, (book+1)*1000000+verse.Chapter*1000+verse.Verse)
+			}
+		}
+		resp.Passages = append(resp.Passages, strings.Join(text, " "))
+		if len(ids) > 0 {
+			resp.Parsed = append(resp.Parsed, []int{ids[0], ids[len(ids)-1]})
+		}
+		canonical = append(canonical, passage.Reference)
+	}
+	resp.Canonical = strings.Join(canonical, "; ")
+	return resp, nil
+}
+
+// Search fails, as bible-api.com has no search.
+func (p *bibleAPIProvider) Search(words string, limit int) ([]SearchResult, error) {
+	return nil, fmt.Errorf("%s comes from bible-api.com, which doesn't support search", p.translation)
+}
+
+func (p *bibleAPIProvider) Random(pool []string, intn func(n int) int) (string, error) {
+	return pickFromPool(pool, intn)
+}
+
+func (p *bibleAPIProvider) WaitForRefreshes() {
+	p.client.WaitForRefreshes()
+}
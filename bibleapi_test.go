@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// bibleAPIVerses maps the references the fake bible-api.com serves to its
+// response bodies.
+var bibleAPIVerses = map[string]string{
+	"John 3:16": `{"reference": "John 3:16", "verses": [
+		{"book_name": "John", "chapter": 3, "verse": 16, "text": "For God so loved the world,\nthat he gave his only begotten Son"}]}`,
+	"Psalm 23:1-2": `{"reference": "Psalm 23:1-2", "verses": [
+		{"book_name": "Psalms", "chapter": 23, "verse": 1, "text": "The LORD is my shepherd; I shall not want.\n"},
+		{"book_name": "Psalms", "chapter": 23, "verse": 2, "text": "He maketh me to lie down in green pastures."}]}`,
+	"Nothing 1:1": `{"reference": "", "verses": []}`,
+}
+
+// newTestBibleAPIProvider returns a provider of translation fetching from a
+// fake bible-api.com.
+func newTestBibleAPIProvider(t *testing.T, translation string, options FetchOptions) *bibleAPIProvider {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("request to bible-api.com sent an Authorization header")
+		}
+		if got, want := r.URL.Query().Get("translation"), strings.ToLower(translation); got != want {
+			t.Errorf("translation = %q, want %q", got, want)
+		}
+		body, ok := bibleAPIVerses[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.Error(w, `{"error": "not found"}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	provider := newBibleAPIProvider(NewBibleClient("", options), translation)
+	provider.baseURL = server.URL + "/"
+	return provider
+}
+
+func TestBibleAPIFetchVerse(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		options       FetchOptions
+		wantPassages  []string
+		wantCanonical string
+		wantParsed    [][]int
+	}{
+		{
+			name:          "verse",
+			query:         "John 3:16",
+			wantPassages:  []string{"For God so loved the world, that he gave his only begotten Son"},
+			wantCanonical: "John 3:16",
+			wantParsed:    [][]int{{43003016, 43003016}},
+		},
+		{
+			name:          "several references",
+			query:         "John 3:16; Psalm 23:1-2",
+			wantPassages:  []string{"For God so loved the world, that he gave his only begotten Son", "The LORD is my shepherd; I shall not want. He maketh me to lie down in green pastures."},
+			wantCanonical: "John 3:16; Psalm 23:1-2",
+			wantParsed:    [][]int{{43003016, 43003016}, {19023001, 19023002}},
+		},
+		{
+			name:          "verse numbers",
+			query:         "Psalm 23:1-2",
+			options:       FetchOptions{VerseNumbers: true},
+			wantPassages:  []string{"[1] The LORD is my shepherd; I shall not want. [2] He maketh me to lie down in green pastures."},
+			wantCanonical: "Psalm 23:1-2",
+			wantParsed:    [][]int{{19023001, 19023002}},
+		},
+		{name: "no verses", query: "Nothing 1:1"},
+		{name: "not found", query: "Nowhere 1:1"},
+		{name: "one of several not found", query: "John 3:16; Nowhere 1:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestBibleAPIProvider(t, "KJV", tt.options)
+			verse, err := provider.FetchVerse(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(verse.Passages, tt.wantPassages) {
+				t.Errorf("FetchVerse(%q) passages = %q, want %q", tt.query, verse.Passages, tt.wantPassages)
+			}
+			if verse.Canonical != tt.wantCanonical {
+				t.Errorf("FetchVerse(%q) canonical = %q, want %q", tt.query, verse.Canonical, tt.wantCanonical)
+			}
+			if !slices.EqualFunc(verse.Parsed, tt.wantParsed, slices.Equal) {
+				t.Errorf("FetchVerse(%q) parsed = %v, want %v", tt.query, verse.Parsed, tt.wantParsed)
+			}
+		})
+	}
+}
+
+func TestBibleAPIUnsupported(t *testing.T) {
+	provider := newTestBibleAPIProvider(t, "WEB", FetchOptions{HTML: true})
+	if _, err := provider.FetchVerse("John 3:16"); err == nil || !strings.Contains(err.Error(), "-with-html") {
+		t.Errorf("FetchVerse with -with-html error = %v, want one naming -with-html", err)
+	}
+	if _, err := provider.Search("living water", 10); err == nil || !strings.Contains(err.Error(), "search") {
+		t.Errorf("Search error = %v, want one saying there is no search", err)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Bookmark is a passage the user saved, with an optional note and topics.
+type Bookmark struct {
+	Reference string   `json:"reference"`
+	Note      string   `json:"note,omitempty"`
+	Topics    []string `json:"topics,omitempty"`
+}
+
+// topicsFlag collects repeated -topic flags.
+type topicsFlag []string
+
+func (t *topicsFlag) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *topicsFlag) Set(value string) error {
+	for topic := range strings.SplitSeq(value, ",") {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			*t = append(*t, topic)
+		}
+	}
+	return nil
+}
+
+// checkBookmark reports why b can't be bookmarked, if it can't.
+func checkBookmark(b Bookmark) error {
+	if strings.TrimSpace(b.Reference) == "" {
+		return fmt.Errorf("missing reference")
+	}
+	if _, ok := queryVerses(b.Reference); !ok {
+		return fmt.Errorf("invalid reference %q", b.Reference)
+	}
+	for _, topic := range b.Topics {
+		if strings.TrimSpace(topic) == "" {
+			return fmt.Errorf("%s: empty topic", b.Reference)
+		}
+	}
+	return nil
+}
+
+// addBookmark adds b to the bookmarks and reports whether it is new. A
+// passage that is already bookmarked keeps its note, or takes the note of b
+// if it has none, and gains the topics of b it lacks.
+func (s *State) addBookmark(b Bookmark) bool {
+	b.Reference = normalizeReference(b.Reference)
+	// Bookmarks edited into the state file by hand may not be normalized
+	i := slices.IndexFunc(s.Bookmarks, func(existing Bookmark) bool {
+		return strings.EqualFold(normalizeReference(existing.Reference), b.Reference)
+	})
+	if i < 0 {
+		s.Bookmarks = append(s.Bookmarks, b)
+		return true
+	}
+	existing := &s.Bookmarks[i]
+	if existing.Note == "" {
+		existing.Note = b.Note
+	}
+	for _, topic := range b.Topics {
+		if !slices.Contains(existing.Topics, topic) {
+			existing.Topics = append(existing.Topics, topic)
+		}
+	}
+	return false
+}
+
+// runBookmarks implements the bookmarks command: on its own it lists the
+// bookmarks, and "bookmarks add REFERENCE" saves one. It returns the exit
+// code.
+func runBookmarks(args []string, opts DisplayOptions) int {
+	flags := flag.NewFlagSet("bookmarks", flag.ContinueOnError)
+	note := flags.String("note", "", "Note to save with the bookmark")
+	var topics topicsFlag
+	flags.Var(&topics, "topic", "Topic to file the bookmark under (repeatable, or comma-separated)")
+	jsonOutput := flags.Bool("json", opts.Format == formatJSON, "Print JSON")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+
+	state, err := loadState()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if len(args) == 0 {
+		if *jsonOutput {
+			return printJSON(state.Bookmarks, opts.CompactJSON)
+		}
+		for _, b := range state.Bookmarks {
+			line := b.Reference
+			if len(b.Topics) > 0 {
+				line += " [" + strings.Join(b.Topics, ", ") + "]"
+			}
+			if b.Note != "" {
+				line += " — " + b.Note
+			}
+			fmt.Fprintln(stdout, line)
+		}
+		return 0
+	}
+	if args[0] != "add" || len(args) == 1 {
+		fmt.Fprintln(os.Stderr, "Error: want 'bookmarks' or 'bookmarks add REFERENCE'")
+		return 2
+	}
+
+	b := Bookmark{Reference: strings.Join(args[1:], " "), Note: *note, Topics: topics}
+	if err := checkBookmark(b); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if !state.addBookmark(b) {
+		fmt.Fprintf(os.Stderr, "%s was already bookmarked\n", normalizeReference(b.Reference))
+	}
+	if err := saveState(state); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: saving bookmarks: %v\n", err)
+		return 1
+	}
+	return 0
+}
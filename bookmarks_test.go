@@ -0,0 +1,82 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAddBookmark(t *testing.T) {
+	existing := []Bookmark{
+		{Reference: "John 3:16", Note: "God's love", Topics: []string{"love"}},
+		{Reference: "Psalm 23"},
+	}
+	tests := []struct {
+		name    string
+		add     Bookmark
+		wantNew bool
+		want    []Bookmark
+	}{
+		{
+			name:    "new",
+			add:     Bookmark{Reference: "rom 8:28", Topics: []string{"hope"}},
+			wantNew: true,
+			want:    append(slices.Clone(existing), Bookmark{Reference: "Romans 8:28", Topics: []string{"hope"}}),
+		},
+		{
+			name: "duplicate keeps its note",
+			add:  Bookmark{Reference: "John 3:16", Note: "another note"},
+			want: existing,
+		},
+		{
+			name: "duplicate in other case and spacing",
+			add:  Bookmark{Reference: "JOHN  3:16", Topics: []string{"love", "salvation"}},
+			want: []Bookmark{{Reference: "John 3:16", Note: "God's love", Topics: []string{"love", "salvation"}}, existing[1]},
+		},
+		{
+			name: "duplicate takes the note it lacks",
+			add:  Bookmark{Reference: "psalm 23", Note: "The shepherd", Topics: []string{"comfort"}},
+			want: []Bookmark{existing[0], {Reference: "Psalm 23", Note: "The shepherd", Topics: []string{"comfort"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := State{Bookmarks: cloneBookmarks(existing)}
+			if got := state.addBookmark(tt.add); got != tt.wantNew {
+				t.Errorf("addBookmark(%q) = %v, want %v", tt.add.Reference, got, tt.wantNew)
+			}
+			if !slices.EqualFunc(state.Bookmarks, tt.want, equalBookmarks) {
+				t.Errorf("bookmarks = %+v, want %+v", state.Bookmarks, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckBookmark(t *testing.T) {
+	tests := []struct {
+		bookmark Bookmark
+		wantErr  bool
+	}{
+		{Bookmark{Reference: "John 3:16"}, false},
+		{Bookmark{Reference: "Psalm 23", Topics: []string{"comfort"}}, false},
+		{Bookmark{Reference: " "}, true},
+		{Bookmark{Reference: "Hezekiah 1:1"}, true},
+		{Bookmark{Reference: "John 3:16", Topics: []string{"love", " "}}, true},
+	}
+	for _, tt := range tests {
+		if err := checkBookmark(tt.bookmark); (err != nil) != tt.wantErr {
+			t.Errorf("checkBookmark(%+v) = %v, want error %v", tt.bookmark, err, tt.wantErr)
+		}
+	}
+}
+
+func cloneBookmarks(bookmarks []Bookmark) []Bookmark {
+	clone := slices.Clone(bookmarks)
+	for i := range clone {
+		clone[i].Topics = slices.Clone(clone[i].Topics)
+	}
+	return clone
+}
+
+func equalBookmarks(a, b Bookmark) bool {
+	return a.Reference == b.Reference && a.Note == b.Note && slices.Equal(a.Topics, b.Topics)
+}
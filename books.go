@@ -0,0 +1,344 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//go:embed books.json
+var booksJSON []byte
+
+// Book is one book of the Bible with the abbreviations accepted for it and
+// the number of verses in each of its chapters.
+type Book struct {
+	Name          string   `json:"name"`
+	Abbreviations []string `json:"abbreviations"`
+	Chapters      []int    `json:"chapters"`
+}
+
+// Verses returns the number of verses in the book.
+func (b Book) Verses() int {
+	total := 0
+	for _, verses := range b.Chapters {
+		total += verses
+	}
+	return total
+}
+
+type BooksData struct {
+	Books []Book `json:"books"`
+}
+
+// bibleBooks lists the books in canonical order.
+var bibleBooks []Book
+
+// bookIndex maps a book name or abbreviation, as normalized by bookKey, to
+// its position in bibleBooks.
+var bookIndex map[string]int
+
+func init() {
+	var data BooksData
+	if err := json.Unmarshal(booksJSON, &data); err != nil {
+		panic(fmt.Sprintf("Failed to load books: %v", err))
+	}
+	bibleBooks = data.Books
+
+	bookIndex = make(map[string]int)
+	for i, book := range bibleBooks {
+		bookIndex[bookKey(book.Name)] = i
+		for _, abbr := range book.Abbreviations {
+			bookIndex[bookKey(abbr)] = i
+		}
+	}
+}
+
+// Testaments, as accepted in the config file.
+const (
+	testamentOld = "OT"
+	testamentNew = "NT"
+)
+
+// oldTestamentBooks is the number of books in the Old Testament, which come
+// first in bibleBooks.
+const oldTestamentBooks = 39
+
+// bookTestament returns the testament of the book at position book.
+func bookTestament(book int) string {
+	if book < oldTestamentBooks {
+		return testamentOld
+	}
+	return testamentNew
+}
+
+func isTestament(name string) bool {
+	return strings.EqualFold(name, testamentOld) || strings.EqualFold(name, testamentNew)
+}
+
+// bookKey normalizes a book name for lookup, so "1 Cor.", "1cor" and
+// "1 COR" all match.
+func bookKey(name string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", ".", "").Replace(name))
+}
+
+// lookupBook returns the position of the named book in bibleBooks.
+func lookupBook(name string) (int, bool) {
+	i, ok := bookIndex[bookKey(name)]
+	return i, ok
+}
+
+// referenceKey locates the start of a reference in canonical order.
+type referenceKey struct {
+	Book    int // Position in bibleBooks
+	Chapter int
+	Verse   int // 0 when the reference names a whole chapter
+}
+
+func (k referenceKey) Less(other referenceKey) bool {
+	if k.Book != other.Book {
+		return k.Book < other.Book
+	}
+	if k.Chapter != other.Chapter {
+		return k.Chapter < other.Chapter
+	}
+	return k.Verse < other.Verse
+}
+
+var (
+	// referencePattern splits a reference into the book name and whatever
+	// follows it, e.g. "1 John" and "4:19".
+	referencePattern = regexp.MustCompile(`^\s*((?:[1-3]\s*)?[^\d]+?)\s*(\d.*)?$`)
+
+	// locationPattern matches the chapter and optional verse at the start of
+	// the part after the book name.
+	locationPattern = regexp.MustCompile(`^(\d+)(?:\s*:\s*(\d+))?`)
+)
+
+// splitReference splits a reference into its book name and the chapter and
+// verse part that follows it.
+func splitReference(reference string) (book, location string, ok bool) {
+	m := referencePattern.FindStringSubmatch(reference)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], strings.TrimSpace(m[2]), true
+}
+
+// normalizeReference rewrites reference with the full book name and
+// without stray whitespace, so "jn 3 : 16" and "John 3:16" compare equal.
+// References with an unknown book only have their whitespace collapsed.
+func normalizeReference(reference string) string {
+	name, location, ok := splitReference(reference)
+	if !ok {
+		return strings.Join(strings.Fields(reference), " ")
+	}
+	book, ok := lookupBook(name)
+	if !ok {
+		return strings.Join(strings.Fields(reference), " ")
+	}
+
+	location = strings.Join(strings.Fields(location), "")
+	if location == "" {
+		return bibleBooks[book].Name
+	}
+	return bibleBooks[book].Name + " " + location
+}
+
+// parseReference returns where reference starts in canonical order. Only the
+// start of a range is considered.
+func parseReference(reference string) (referenceKey, bool) {
+	name, location, ok := splitReference(reference)
+	if !ok {
+		return referenceKey{}, false
+	}
+	book, ok := lookupBook(name)
+	if !ok {
+		return referenceKey{}, false
+	}
+
+	key := referenceKey{Book: book, Chapter: 1}
+	if m := locationPattern.FindStringSubmatch(location); m != nil {
+		key.Chapter, _ = strconv.Atoi(m[1])
+		if m[2] != "" {
+			key.Verse, _ = strconv.Atoi(m[2])
+		}
+	}
+	return key, true
+}
+
+// verseIDKey returns the verse with the given API verse id, which packs the
+// book, chapter and verse as BBCCCVVV, e.g. 43003016 for John 3:16.
+func verseIDKey(id int) referenceKey {
+	return referenceKey{Book: id/1000000 - 1, Chapter: id / 1000 % 1000, Verse: id % 1000}
+}
+
+// versesBetween counts the verses from start to end, both included.
+func versesBetween(start, end referenceKey) int {
+	total := 0
+	for k := start; !end.Less(k); {
+		if k.Book < 0 || k.Book >= len(bibleBooks) {
+			break
+		}
+		chapters := bibleBooks[k.Book].Chapters
+		if k.Chapter < 1 || k.Chapter > len(chapters) {
+			break
+		}
+		last := chapters[k.Chapter-1]
+		if k.Book == end.Book && k.Chapter == end.Chapter {
+			last = min(last, end.Verse)
+		}
+		total += max(last-max(k.Verse, 1)+1, 0)
+
+		k.Chapter, k.Verse = k.Chapter+1, 1
+		if k.Chapter > len(chapters) {
+			k.Book, k.Chapter = k.Book+1, 1
+		}
+	}
+	return total
+}
+
+// locationSpan returns the first and last verse of a location within book:
+// the whole book when location is empty, a whole chapter for "3" and a
+// single verse for "3:16". Anything after the chapter and verse is ignored.
+func locationSpan(book int, location string) (start, end referenceKey, ok bool) {
+	chapters := bibleBooks[book].Chapters
+	if location == "" {
+		last := len(chapters)
+		return referenceKey{book, 1, 1}, referenceKey{book, last, chapters[last-1]}, true
+	}
+	m := locationPattern.FindStringSubmatch(location)
+	if m == nil {
+		return start, end, false
+	}
+	chapter, _ := strconv.Atoi(m[1])
+	if chapter < 1 || chapter > len(chapters) {
+		return start, end, false
+	}
+	if m[2] == "" {
+		return referenceKey{book, chapter, 1}, referenceKey{book, chapter, chapters[chapter-1]}, true
+	}
+	verse, _ := strconv.Atoi(m[2])
+	return referenceKey{book, chapter, verse}, referenceKey{book, chapter, verse}, true
+}
+
+// referenceSpan returns the first and last verse of a single reference or
+// range, e.g. "John 3:16-18", "Psalm 23-24", "Gen 1:1-Exod 2:3" or
+// "Genesis-Revelation".
+func referenceSpan(reference string) (start, end referenceKey, ok bool) {
+	reference = strings.ReplaceAll(reference, "–", "-")
+	left, right, isRange := strings.Cut(reference, "-")
+	name, location, ok := splitReference(left)
+	if !ok {
+		return start, end, false
+	}
+	book, ok := lookupBook(name)
+	if !ok {
+		return start, end, false
+	}
+	start, end, ok = locationSpan(book, location)
+	if !ok || !isRange {
+		return start, end, ok
+	}
+
+	// The end of a range names another book or continues the start: a verse
+	// after "3:16", a chapter after "3" or a chapter and verse
+	right = strings.TrimSpace(right)
+	if name, location, found := splitReference(right); found {
+		if book, found := lookupBook(name); found {
+			_, end, ok = locationSpan(book, location)
+			return start, end, ok
+		}
+	}
+	m := locationPattern.FindStringSubmatch(right)
+	if m == nil {
+		return start, end, false
+	}
+	n, _ := strconv.Atoi(m[1])
+	switch {
+	case m[2] != "":
+		_, end, ok = locationSpan(start.Book, right)
+	case strings.Contains(location, ":"):
+		end.Verse = n
+	default:
+		_, end, ok = locationSpan(start.Book, m[1])
+	}
+	return start, end, ok
+}
+
+// queryVerses counts the verses in a query of references separated by
+// semicolons. It reports false if any of them can't be parsed.
+func queryVerses(query string) (int, bool) {
+	total := 0
+	for _, reference := range strings.Split(query, ";") {
+		start, end, ok := referenceSpan(reference)
+		if !ok {
+			return 0, false
+		}
+		total += versesBetween(start, end)
+	}
+	return total, true
+}
+
+// parsedVerses counts the verses in the ranges of verse ids the API returns
+// as the parsed form of a query.
+func parsedVerses(parsed [][]int) int {
+	total := 0
+	for _, r := range parsed {
+		switch len(r) {
+		case 1:
+			total++
+		case 2:
+			total += versesBetween(verseIDKey(r[0]), verseIDKey(r[1]))
+		}
+	}
+	return total
+}
+
+// runBooks implements the books command, listing the books with their
+// chapter and verse counts, or with -chapters the verses in each chapter of
+// one book. It returns the exit code.
+func runBooks(args []string, opts DisplayOptions) int {
+	flags := flag.NewFlagSet("books", flag.ContinueOnError)
+	chapters := flags.String("chapters", "", "List the verses in each chapter of this book")
+	jsonOutput := flags.Bool("json", opts.Format == formatJSON, "Print JSON")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) > 0 {
+		// Allow multi-word names without quotes: books -chapters 1 John
+		*chapters = strings.TrimSpace(strings.Join(append([]string{*chapters}, args...), " "))
+	}
+
+	if *chapters == "" {
+		if *jsonOutput {
+			return printJSON(bibleBooks, opts.CompactJSON)
+		}
+		fmt.Fprintf(stdout, "%-16s %8s %6s\n", "Book", "Chapters", "Verses")
+		for _, book := range bibleBooks {
+			fmt.Fprintf(stdout, "%-16s %8d %6d\n", book.Name, len(book.Chapters), book.Verses())
+		}
+		return 0
+	}
+
+	i, ok := lookupBook(*chapters)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown book %q\n", *chapters)
+		return 1
+	}
+	book := bibleBooks[i]
+	if *jsonOutput {
+		return printJSON(book, opts.CompactJSON)
+	}
+	fmt.Fprintf(stdout, "%s: %d chapters, %d verses\n\n", book.Name, len(book.Chapters), book.Verses())
+	fmt.Fprintf(stdout, "%7s %6s\n", "Chapter", "Verses")
+	for chapter, verses := range book.Chapters {
+		fmt.Fprintf(stdout, "%7d %6d\n", chapter+1, verses)
+	}
+	return 0
+}
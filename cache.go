@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultCacheTTL is how long a cached response is served without asking
+// the API again. Passage text practically never changes.
+const defaultCacheTTL = 7 * 24 * time.Hour
+
+// Cache backends, as selected with -cache-backend.
+const (
+	cacheDisk   = "disk"   // Kept across runs in the user's cache directory
+	cacheMemory = "memory" // Kept for the life of the process
+	cacheNone   = "none"   // Not kept at all
+)
+
+// responseCache stores API response bodies keyed by request URL.
+type responseCache interface {
+	// Get returns the entry cached for key, if any.
+	Get(key string) (entry cacheEntry, found bool)
+
+	// Put stores body for key. Failures only cost a future cache miss, so
+	// they are logged rather than returned.
+	Put(key string, body []byte)
+}
+
+// cacheEntry is a response body found in a responseCache.
+type cacheEntry struct {
+	Body   []byte
+	Stored time.Time // When the body was put in the cache
+	Fresh  bool      // Whether it is younger than the cache's TTL
+}
+
+// newResponseCache returns the cache backend named by backend, one of the
+// cache constants.
+func newResponseCache(backend string, ttl time.Duration) (responseCache, error) {
+	switch backend {
+	case cacheDisk:
+		return newDiskCache(ttl)
+	case cacheMemory:
+		return newMemoryCache(ttl), nil
+	}
+	return nopCache{}, nil
+}
+
+// nopCache is a responseCache that never has anything cached.
+type nopCache struct{}
+
+func (nopCache) Get(string) (cacheEntry, bool) { return cacheEntry{}, false }
+func (nopCache) Put(string, []byte)            {}
+
+// memoryCache is a responseCache held in memory, shared by concurrent
+// fetches.
+type memoryCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	body   []byte
+	stored time.Time
+}
+
+func newMemoryCache(ttl time.Duration) *memoryCache {
+	return &memoryCache{ttl: ttl, entries: make(map[string]memoryEntry)}
+}
+
+func (c *memoryCache) Get(key string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, found := c.entries[key]
+	if !found {
+		return cacheEntry{}, false
+	}
+	return cacheEntry{Body: entry.body, Stored: entry.stored, Fresh: time.Since(entry.stored) < c.ttl}, true
+}
+
+func (c *memoryCache) Put(key string, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = memoryEntry{body: body, stored: time.Now()}
+}
+
+// diskCache stores API response bodies in the user's cache directory, keyed
+// by request URL.
+type diskCache struct {
+	dir string
+	ttl time.Duration
+}
+
+func newDiskCache(ttl time.Duration) (*diskCache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &diskCache{dir: filepath.Join(dir, "bible-cli"), ttl: ttl}, nil
+}
+
+func (c *diskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get takes the modification time of an entry's file as when it was stored.
+func (c *diskCache) Get(key string) (cacheEntry, bool) {
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return cacheEntry{}, false
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return cacheEntry{}, false
+	}
+	return cacheEntry{Body: body, Stored: info.ModTime(), Fresh: time.Since(info.ModTime()) < c.ttl}, true
+}
+
+func (c *diskCache) Put(key string, body []byte) {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		debugLog.Printf("creating cache directory: %v", err)
+		return
+	}
+
+	// Written through a temporary file so readers never see partial entries
+	if err := writeFileAtomic(c.path(key), body, 0o600); err != nil {
+		debugLog.Printf("writing cache: %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseCacheBackends(t *testing.T) {
+	tests := []struct {
+		backend   string
+		wantFound bool
+	}{
+		{cacheMemory, true},
+		{cacheDisk, true},
+		{cacheNone, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			t.Setenv("XDG_CACHE_HOME", t.TempDir())
+			for _, ttl := range []time.Duration{time.Hour, 0} {
+				cache, err := newResponseCache(tt.backend, ttl)
+				if err != nil {
+					t.Fatalf("newResponseCache(%q): %v", tt.backend, err)
+				}
+				if _, found := cache.Get("missing"); found {
+					t.Errorf("ttl %v: Get of a missing key found an entry", ttl)
+				}
+
+				key := fmt.Sprintf("https://api.esv.org/v3/passage/text/?q=John+3:16&ttl=%v", ttl)
+				before := time.Now()
+				cache.Put(key, []byte("body"))
+				entry, found := cache.Get(key)
+				if found != tt.wantFound {
+					t.Fatalf("ttl %v: Get found = %v, want %v", ttl, found, tt.wantFound)
+				}
+				if !found {
+					continue
+				}
+				if string(entry.Body) != "body" {
+					t.Errorf("ttl %v: Get = %q, want %q", ttl, entry.Body, "body")
+				}
+				if want := ttl > 0; entry.Fresh != want {
+					t.Errorf("ttl %v: Get fresh = %v, want %v", ttl, entry.Fresh, want)
+				}
+				// File times may be coarser than the clock
+				if entry.Stored.Before(before.Add(-time.Second)) || entry.Stored.After(time.Now()) {
+					t.Errorf("ttl %v: Get stored = %v, want about %v", ttl, entry.Stored, before)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchVerseUsesCache(t *testing.T) {
+	tests := []struct {
+		backend      string
+		wantRequests int
+	}{
+		{cacheMemory, 1},
+		{cacheDisk, 1},
+		{cacheNone, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			t.Setenv("XDG_CACHE_HOME", t.TempDir())
+			cache, err := newResponseCache(tt.backend, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			requests := 0
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				fmt.Fprint(w, `{"canonical": "John 3:16", "passages": ["For God so loved the world"]}`)
+			}, FetchOptions{Cache: cache})
+
+			for range 2 {
+				verse, err := client.FetchVerse("John 3:16")
+				if err != nil {
+					t.Fatalf("FetchVerse: %v", err)
+				}
+				if verse.Canonical != "John 3:16" {
+					t.Errorf("Canonical = %q, want John 3:16", verse.Canonical)
+				}
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("made %d requests, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestFetchVerseRawResponseSkipsCache(t *testing.T) {
+	const body = `{"canonical": "John 3:16", "passages": ["For God so loved the world"]}`
+	requests := 0
+	var raw bytes.Buffer
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, body)
+	}, FetchOptions{Cache: newMemoryCache(time.Hour), RawResponse: &raw})
+
+	for i := range 2 {
+		if _, err := client.FetchVerse("John 3:16"); err != nil {
+			t.Fatalf("FetchVerse: %v", err)
+		}
+		if got, want := raw.String(), strings.Repeat(body, i+1); got != want {
+			t.Errorf("after fetch %d, raw response = %q, want %q", i+1, got, want)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("made %d requests, want 2", requests)
+	}
+}
+
+func TestFetchVerseCachedFetchTime(t *testing.T) {
+	tests := []struct {
+		name         string
+		ttl          time.Duration
+		staleTimeout time.Duration
+		serverDelay  time.Duration
+		wantStale    bool
+	}{
+		{name: "fresh", ttl: 24 * time.Hour},
+		{name: "stale", ttl: time.Minute, staleTimeout: time.Millisecond, serverDelay: 200 * time.Millisecond, wantStale: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newMemoryCache(tt.ttl)
+			delay := time.Duration(0)
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(delay)
+				fmt.Fprint(w, `{"canonical": "John 3:16", "passages": ["For God so loved the world"]}`)
+			}, FetchOptions{Cache: cache, StaleTimeout: tt.staleTimeout})
+
+			first, err := client.FetchVerse("John 3:16")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if since := time.Since(first.Fetched); since < 0 || since > 2*time.Second {
+				t.Errorf("live fetch Fetched = %v, want about now", first.Fetched)
+			}
+
+			// Pretend the entry was stored an hour ago
+			stored := time.Now().Add(-time.Hour)
+			for key, entry := range cache.entries {
+				entry.stored = stored
+				cache.entries[key] = entry
+			}
+			delay = tt.serverDelay
+			verse, err := client.FetchVerse("John 3:16")
+			if err != nil {
+				t.Fatal(err)
+			}
+			client.WaitForRefreshes()
+			if want := stored.Truncate(time.Second); !verse.Fetched.Equal(want) || verse.Stale != tt.wantStale {
+				t.Errorf("cached fetch = Fetched %v, stale %v, want %v, %v", verse.Fetched, verse.Stale, want, tt.wantStale)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// clipboardCommands lists the clipboard tools tried, in order, for writing
+// to the clipboard on this platform.
+func clipboardCommands() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip.exe"}}
+	}
+
+	var commands [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		commands = append(commands, []string{"wl-copy"})
+	}
+	commands = append(commands,
+		[]string{"xclip", "-selection", "clipboard"},
+		[]string{"xsel", "--clipboard", "--input"},
+		[]string{"clip.exe"}, // WSL
+	)
+	return commands
+}
+
+// copyToClipboard puts text on the system clipboard using the first
+// available clipboard tool. Some tools warn on stderr even when copying
+// works, so their stderr is only shown when they fail.
+func copyToClipboard(text string) error {
+	for _, command := range clipboardCommands() {
+		path, err := exec.LookPath(command[0])
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, command[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return fmt.Errorf("%s: %w: %s", command[0], err, msg)
+			}
+			return fmt.Errorf("%s: %w", command[0], err)
+		}
+		if stderr.Len() > 0 {
+			debugLog.Printf("%s: %s", command[0], strings.TrimSpace(stderr.String()))
+		}
+		return nil
+	}
+	return errors.New("no clipboard tool found (install wl-clipboard, xclip or xsel)")
+}
+
+// pasteCommands lists the clipboard tools tried, in order, for reading the
+// clipboard on this platform.
+func pasteCommands() [][]string {
+	powershell := []string{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard"}
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbpaste"}}
+	case "windows":
+		return [][]string{powershell}
+	}
+
+	var commands [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		commands = append(commands, []string{"wl-paste", "--no-newline"})
+	}
+	commands = append(commands,
+		[]string{"xclip", "-selection", "clipboard", "-out"},
+		[]string{"xsel", "--clipboard", "--output"},
+		powershell, // WSL
+	)
+	return commands
+}
+
+// readClipboard returns the text on the system clipboard, read with the
+// first available clipboard tool.
+func readClipboard() (string, error) {
+	for _, command := range pasteCommands() {
+		path, err := exec.LookPath(command[0])
+		if err != nil {
+			continue
+		}
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(path, command[1:]...)
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return "", fmt.Errorf("%s: %w: %s", command[0], err, msg)
+			}
+			return "", fmt.Errorf("%s: %w", command[0], err)
+		}
+		return stdout.String(), nil
+	}
+	return "", errors.New("no clipboard tool found (install wl-clipboard, xclip or xsel)")
+}
+
+// textReferencePattern matches what may be a reference at the start of
+// other text: a book name, possibly numbered or with "of" in it, followed by
+// a chapter and optionally verses, e.g. "1 John 4:19" or "Song of Songs 2:1-4".
+var textReferencePattern = regexp.MustCompile(`(?i)^(?:[1-3]\s*)?[a-z][a-z.]*(?:\s+of\s+[a-z]+)?\s*\d+(?:\s*:\s*\d+)?(?:\s*[-–]\s*\d+(?:\s*:\s*\d+)?)?`)
+
+// referenceCandidates returns what may be references in text, trying a
+// match at the start of every word. Matches may overlap, so in "Read 1 John
+// 4:19" both "1 John 4:19" and "John 4:19" are found, the longer first.
+func referenceCandidates(text string) []string {
+	var candidates []string
+	prev := ' '
+	for i, r := range text {
+		wordStart := !isWordRune(prev) && isWordRune(r)
+		prev = r
+		if !wordStart {
+			continue
+		}
+		if match := textReferencePattern.FindString(text[i:]); match != "" {
+			candidates = append(candidates, match)
+		}
+	}
+	return candidates
+}
+
+// isWordRune reports whether r is part of a word for referenceCandidates.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// findReference extracts the first reference from text, such as a sentence
+// quoting one. Book names are matched exactly first, then allowing for
+// typos. It returns the normalized reference.
+func findReference(text string) (string, bool) {
+	matches := referenceCandidates(text)
+	for _, fuzzy := range []bool{false, true} {
+		for _, match := range matches {
+			name, location, ok := splitReference(strings.ReplaceAll(match, "–", "-"))
+			if !ok || location == "" {
+				continue
+			}
+			book, ok := lookupBook(name)
+			if !ok && fuzzy && len(bookKey(name)) >= 3 {
+				if books := fuzzyBookMatches(name); len(books) > 0 {
+					book, ok = books[0], true
+				}
+			}
+			if ok {
+				return normalizeReference(bibleBooks[book].Name + " " + location), true
+			}
+		}
+	}
+	return "", false
+}
+
+// verseNumberPattern matches the verse number markers the API puts in the
+// text, e.g. "[16] " or "[3:1] ".
+var verseNumberPattern = regexp.MustCompile(`\[\d+(?::\d+)?\]\s*`)
+
+// stripVerseNumbers removes verse number markers from text.
+func stripVerseNumbers(text string) string {
+	return verseNumberPattern.ReplaceAllString(text, "")
+}
+
+// clipboardText returns the passages of verse as copied to the clipboard:
+// the unwrapped text of each passage followed by its reference.
+func clipboardText(verse *ESVResponse, opts DisplayOptions) string {
+	var b strings.Builder
+	for i, passage := range verse.Passages {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		text := cleanPassage(passage, opts)
+		if opts.StripNumbers {
+			text = stripVerseNumbers(text)
+		}
+		for _, line := range strings.Split(text, "\n") {
+			b.WriteString(foldWhitespace(line, opts.Whitespace == whitespaceIndent))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+		b.WriteString(passageHeader(verse, i, opts))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
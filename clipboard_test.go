@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// stubClipboardTool puts a shell script named xclip, running script, first
+// in PATH so clipboard functions use it.
+func stubClipboardTool(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("clipboard tool stubs need xclip to be tried")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "xclip"), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("WAYLAND_DISPLAY", "")
+}
+
+func TestCopyToClipboardStderr(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		wantErr string
+	}{
+		{"succeeds", "cat >/dev/null\n", ""},
+		{"warns but succeeds", "cat >/dev/null\necho 'Warning: no display' >&2\n", ""},
+		{"fails", "cat >/dev/null\necho 'Error: cannot open display' >&2\nexit 1\n", "cannot open display"},
+		{"fails quietly", "exit 1\n", "exit status 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubClipboardTool(t, tt.script)
+			err := copyToClipboard("John 3:16")
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("copyToClipboard() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("copyToClipboard() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCopyToClipboardNoTool(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("the platform clipboard tool is always present")
+	}
+	t.Setenv("PATH", t.TempDir())
+	if err := copyToClipboard("John 3:16"); err == nil {
+		t.Error("copyToClipboard() = nil, want an error when no tool is installed")
+	}
+}
+
+func TestFindReference(t *testing.T) {
+	tests := []struct {
+		text   string
+		want   string
+		wantOK bool
+	}{
+		{"John 3:16", "John 3:16", true},
+		{"Read 1 John 4:19 today", "1 John 4:19", true},
+		{"see 2 Kings 2:11.", "2 Kings 2:11", true},
+		{"(1 Cor 13:4-7)", "1 Corinthians 13:4-7", true},
+		{"Today's verse is Song of Songs 2:1–4, enjoy", "Song of Solomon 2:1-4", true},
+		{"Chapter 1 John 1:1", "1 John 1:1", true},
+		{"John 3:16 and 1 John 4:19", "John 3:16", true},
+		{"Read 1 Jhon 4:19", "1 John 4:19", true},
+		{"Romans chapter 8", "", false},
+		{"nothing here", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := findReference(tt.text)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("findReference(%q) = %q, %v, want %q, %v", tt.text, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// Clocks the day of the daily verse can be taken from, with -clock.
+const (
+	clockLocal = "local" // The local date, which changes at local midnight
+	clockUTC   = "utc"   // The UTC date, the same everywhere at once
+	clockNTP   = "ntp"   // The local date by network time, for machines whose clock is off
+)
+
+var clockSources = []string{clockLocal, clockUTC, clockNTP}
+
+const (
+	ntpServer  = "pool.ntp.org:123"
+	ntpTimeout = 3 * time.Second
+)
+
+// ntpEpochOffset is the number of seconds from the NTP epoch, 1900, to the
+// Unix epoch.
+const ntpEpochOffset = 2208988800
+
+// ntpNow asks server for the current time with a single SNTP request.
+func ntpNow(server string) (time.Time, error) {
+	conn, err := net.DialTimeout("udp", server, ntpTimeout)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(ntpTimeout))
+
+	request := make([]byte, 48)
+	request[0] = 0x23 // Version 4, client mode
+	if _, err := conn.Write(request); err != nil {
+		return time.Time{}, err
+	}
+	response := make([]byte, 48)
+	if n, err := conn.Read(response); err != nil {
+		return time.Time{}, err
+	} else if n < 48 {
+		return time.Time{}, fmt.Errorf("short NTP response (%d bytes)", n)
+	}
+	// The transmit timestamp: seconds and a binary fraction since 1900
+	seconds := binary.BigEndian.Uint32(response[40:])
+	fraction := binary.BigEndian.Uint32(response[44:])
+	if seconds == 0 {
+		return time.Time{}, fmt.Errorf("NTP response without a time")
+	}
+	nanos := int64(fraction) * 1e9 >> 32
+	return time.Unix(int64(seconds)-ntpEpochOffset, nanos), nil
+}
+
+// errNoNetworkTime is the error of verseDayAt when the network time can't
+// be had.
+var errNoNetworkTime = errors.New("no network time")
+
+// verseDay returns the day of the daily verse, with the local time of day
+// for the greeting: date if given, as YYYY-MM-DD, otherwise today by clock.
+// If the network time can't be had, the local clock is used with a warning.
+func verseDay(clock, date string) (time.Time, error) {
+	day, err := verseDayAt(time.Now(), clock, date, func() (time.Time, error) { return ntpNow(ntpServer) })
+	if errors.Is(err, errNoNetworkTime) {
+		fmt.Fprintf(os.Stderr, "Warning: %v; using the local clock\n", err)
+		return day, nil
+	}
+	return day, err
+}
+
+// verseDayAt is verseDay at the time now, asking networkNow for the time
+// for the ntp clock. If that fails, it returns the day by now along with an
+// error wrapping errNoNetworkTime.
+func verseDayAt(now time.Time, clock, date string, networkNow func() (time.Time, error)) (time.Time, error) {
+	day := now
+	var err error
+	switch {
+	case date != "":
+		d, parseErr := time.ParseInLocation(dayLayout, date, now.Location())
+		if parseErr != nil {
+			return time.Time{}, fmt.Errorf("invalid -date %q (want YYYY-MM-DD)", date)
+		}
+		day = d
+	case clock == clockUTC:
+		day = now.UTC()
+	case clock == clockNTP:
+		t, ntpErr := networkNow()
+		if ntpErr != nil {
+			err = fmt.Errorf("%w (%v)", errNoNetworkTime, ntpErr)
+			break
+		}
+		debugLog.Printf("network time is %v, the local clock is off by %v", t, now.Sub(t).Round(time.Millisecond))
+		now = t.In(now.Location())
+		day = now
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), now.Hour(), now.Minute(), now.Second(), 0, now.Location()), err
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestVerseDayAt(t *testing.T) {
+	newYork := time.FixedZone("UTC-5", -5*60*60)
+	tokyo := time.FixedZone("UTC+9", 9*60*60)
+	networkTime := func() (time.Time, error) { return time.Date(2024, 3, 12, 1, 0, 0, 0, time.UTC), nil }
+	noNetwork := func() (time.Time, error) { return time.Time{}, errors.New("timeout") }
+	tests := []struct {
+		name    string
+		now     time.Time
+		clock   string
+		date    string
+		network func() (time.Time, error)
+		want    time.Time
+		wantErr bool
+	}{
+		{"local", time.Date(2024, 3, 10, 23, 30, 0, 0, newYork), clockLocal, "", nil, time.Date(2024, 3, 10, 23, 30, 0, 0, newYork), false},
+		{"utc before local midnight", time.Date(2024, 3, 10, 23, 30, 0, 0, newYork), clockUTC, "", nil, time.Date(2024, 3, 11, 23, 30, 0, 0, newYork), false},
+		{"utc after local midnight", time.Date(2024, 3, 11, 0, 30, 0, 0, tokyo), clockUTC, "", nil, time.Date(2024, 3, 10, 0, 30, 0, 0, tokyo), false},
+		{"utc at utc midnight", time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), clockUTC, "", nil, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), false},
+		{"ntp", time.Date(2024, 3, 10, 12, 0, 0, 0, newYork), clockNTP, "", networkTime, time.Date(2024, 3, 11, 20, 0, 0, 0, newYork), false},
+		{"ntp failure", time.Date(2024, 3, 10, 12, 0, 0, 0, newYork), clockNTP, "", noNetwork, time.Date(2024, 3, 10, 12, 0, 0, 0, newYork), true},
+		{"date", time.Date(2024, 3, 10, 12, 0, 0, 0, newYork), clockLocal, "2024-12-25", nil, time.Date(2024, 12, 25, 12, 0, 0, 0, newYork), false},
+		{"date with utc", time.Date(2024, 3, 10, 23, 30, 0, 0, newYork), clockUTC, "2024-12-25", nil, time.Date(2024, 12, 25, 23, 30, 0, 0, newYork), false},
+		{"date with ntp", time.Date(2024, 3, 10, 12, 0, 0, 0, newYork), clockNTP, "2024-02-29", nil, time.Date(2024, 2, 29, 12, 0, 0, 0, newYork), false},
+	}
+	for _, tt := range tests {
+		network := tt.network
+		if network == nil {
+			network = ntpUnused(t)
+		}
+		got, err := verseDayAt(tt.now, tt.clock, tt.date, network)
+		if !got.Equal(tt.want) || got.Location() != tt.want.Location() {
+			t.Errorf("%s: verseDayAt(%v, %q, %q) = %v, want %v", tt.name, tt.now, tt.clock, tt.date, got, tt.want)
+		}
+		if gotErr := errors.Is(err, errNoNetworkTime); gotErr != tt.wantErr || (err != nil && !gotErr) {
+			t.Errorf("%s: verseDayAt(%v, %q, %q) error = %v, want no network time: %v", tt.name, tt.now, tt.clock, tt.date, err, tt.wantErr)
+		}
+	}
+}
+
+func TestVerseDayAtInvalidDate(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	for _, date := range []string{"2024-13-01", "2023-02-29", "2024-3-1", "12/25/2024", "2024-12-25T00:00:00Z", "today"} {
+		for _, clock := range clockSources {
+			if day, err := verseDayAt(now, clock, date, ntpUnused(t)); err == nil {
+				t.Errorf("verseDayAt(%q, %q) = %v, want an error", clock, date, day)
+			}
+		}
+	}
+}
+
+// ntpUnused returns a network time source that fails the test if used.
+func ntpUnused(t *testing.T) func() (time.Time, error) {
+	return func() (time.Time, error) {
+		t.Error("asked for the network time")
+		return time.Time{}, errors.New("unexpected")
+	}
+}
+
+// serveNTP answers one SNTP request on a local port with response, and
+// returns the address.
+func serveNTP(t *testing.T, response []byte) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no local UDP: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		request := make([]byte, 48)
+		n, addr, err := conn.ReadFrom(request)
+		if err != nil {
+			return
+		}
+		if n != 48 || request[0] != 0x23 {
+			t.Errorf("NTP request = %x, want 48 bytes for a version 4 client", request[:n])
+		}
+		conn.WriteTo(response, addr)
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestNTPNow(t *testing.T) {
+	want := time.Date(2024, 3, 10, 12, 0, 0, 500_000_000, time.UTC)
+	response := make([]byte, 48)
+	binary.BigEndian.PutUint32(response[40:], uint32(want.Unix()+ntpEpochOffset))
+	binary.BigEndian.PutUint32(response[44:], 1<<31) // Half a second
+	got, err := ntpNow(serveNTP(t, response))
+	if err != nil || !got.Equal(want) {
+		t.Errorf("ntpNow() = %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestNTPNowBadResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response []byte
+	}{
+		{"short", make([]byte, 12)},
+		{"without a time", make([]byte, 48)},
+	}
+	for _, tt := range tests {
+		if got, err := ntpNow(serveNTP(t, tt.response)); err == nil {
+			t.Errorf("ntpNow() of a %s response = %v, want an error", tt.name, got)
+		}
+	}
+}
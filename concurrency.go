@@ -0,0 +1,62 @@
+package main
+
+import (
+	"math"
+	"sync"
+)
+
+// adaptiveLimiter bounds the number of requests in flight, halving the
+// bound when the API rate limits a request and slowly raising it again while
+// requests succeed (additive increase, multiplicative decrease).
+type adaptiveLimiter struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	limit    float64
+	min, max float64
+	inFlight int
+}
+
+func newAdaptiveLimiter(initial, min, max int) *adaptiveLimiter {
+	l := &adaptiveLimiter{
+		limit: float64(initial),
+		min:   float64(min),
+		max:   float64(max),
+	}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// Acquire blocks until another request may be sent.
+func (l *adaptiveLimiter) Acquire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.inFlight >= int(math.Floor(l.limit)) {
+		l.cond.Wait()
+	}
+	l.inFlight++
+}
+
+// Release ends a request started with Acquire, adjusting the limit to
+// whether the API rate limited it.
+func (l *adaptiveLimiter) Release(rateLimited bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+
+	before := int(l.limit)
+	if rateLimited {
+		l.limit = math.Max(l.min, l.limit/2)
+	} else {
+		// Grows by about one per limit's worth of successful requests
+		l.limit = math.Min(l.max, l.limit+1/l.limit)
+	}
+	if after := int(l.limit); after != before {
+		debugLog.Printf("concurrency %d -> %d", before, after)
+	}
+	l.cond.Broadcast()
+}
+
+// Max returns the most requests the limiter will ever allow at once.
+func (l *adaptiveLimiter) Max() int {
+	return int(l.max)
+}
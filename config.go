@@ -0,0 +1,241 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Config holds the settings read from the config file. Flags override them.
+type Config struct {
+	// Translation is the default translation.
+	Translation string `json:"translation,omitempty"`
+
+	// Translations maps a book name or abbreviation, or "OT" or "NT" for a
+	// whole testament, to the translation preferred for it.
+	Translations map[string]string `json:"translations,omitempty"`
+
+	// CopyrightVerses is the number of verses above which passages carry
+	// the short copyright notice. Unset means defaultCopyrightVerses.
+	CopyrightVerses *int `json:"copyright_verses,omitempty"`
+
+	// MaxSpanVerses is the most verses a query may span, 0 for no limit.
+	// Unset means defaultMaxSpanVerses.
+	MaxSpanVerses *int `json:"max_span_verses,omitempty"`
+
+	// Books, if not empty, restricts the tool to these books, given by name
+	// or abbreviation, or as "OT" or "NT" for a whole testament.
+	Books []string `json:"books,omitempty"`
+}
+
+// defaultMaxSpanVerses is the most verses a query may span by default: any
+// book fits, even Psalms with its 2461 verses, but not runs of books like
+// the entire Bible.
+const defaultMaxSpanVerses = 2500
+
+// defaultCopyrightVerses is the passage length, in verses, above which the
+// ESV's short copyright notice "(ESV)" is shown unless configured otherwise.
+const defaultCopyrightVerses = 500
+
+// configPath returns the path of the config file: $BIBLE_CLI_CONFIG if set,
+// otherwise bible-cli/config.json in the user's config directory.
+func configPath() (string, error) {
+	if path := os.Getenv("BIBLE_CLI_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bible-cli", "config.json"), nil
+}
+
+// loadConfig reads the config file. A missing file yields the zero Config.
+func loadConfig() (Config, error) {
+	var config Config
+	path, err := configPath()
+	if err != nil {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("reading config: %w", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return config, fmt.Errorf("config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+func (c Config) validate() error {
+	for _, name := range c.Books {
+		if _, ok := lookupBook(name); !ok && !isTestament(name) {
+			return fmt.Errorf("books: %q is not a book or testament (OT or NT)", name)
+		}
+	}
+	if c.CopyrightVerses != nil && *c.CopyrightVerses < 0 {
+		return fmt.Errorf("copyright_verses: %d is negative", *c.CopyrightVerses)
+	}
+	if c.MaxSpanVerses != nil && *c.MaxSpanVerses < 0 {
+		return fmt.Errorf("max_span_verses: %d is negative", *c.MaxSpanVerses)
+	}
+	for key := range c.Translations {
+		if _, ok := lookupBook(key); !ok && !isTestament(key) {
+			return fmt.Errorf("translations: %q is not a book or testament (OT or NT)", key)
+		}
+	}
+	return nil
+}
+
+// bookTranslation returns the translation configured for the book at the
+// given position in bibleBooks, preferring an entry for the book itself over
+// one for its testament. It returns "" when neither is configured.
+func (c Config) bookTranslation(book int) string {
+	var byTestament string
+	for key, translation := range c.Translations {
+		if i, ok := lookupBook(key); ok && i == book {
+			return strings.ToUpper(translation)
+		}
+		if strings.EqualFold(key, bookTestament(book)) {
+			byTestament = strings.ToUpper(translation)
+		}
+	}
+	return byTestament
+}
+
+// copyrightVerses returns the configured copyright threshold, or the default.
+func (c Config) copyrightVerses() int {
+	if c.CopyrightVerses == nil {
+		return defaultCopyrightVerses
+	}
+	return *c.CopyrightVerses
+}
+
+// maxSpanVerses returns the configured maximum span of a query, or the
+// default.
+func (c Config) maxSpanVerses() int {
+	if c.MaxSpanVerses == nil {
+		return defaultMaxSpanVerses
+	}
+	return *c.MaxSpanVerses
+}
+
+// allowsBook reports whether the book at the given position in bibleBooks is
+// one the config restricts the tool to.
+func (c Config) allowsBook(book int) bool {
+	if len(c.Books) == 0 {
+		return true
+	}
+	for _, name := range c.Books {
+		if i, ok := lookupBook(name); ok && i == book || strings.EqualFold(name, bookTestament(book)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBooks returns an error if query names a book outside those the
+// config allows. References that can't be parsed are left to the API.
+func (c Config) checkBooks(query string) error {
+	if len(c.Books) == 0 {
+		return nil
+	}
+	for _, reference := range strings.Split(query, ";") {
+		start, end, ok := referenceSpan(reference)
+		if !ok {
+			continue
+		}
+		for book := start.Book; book <= end.Book; book++ {
+			if !c.allowsBook(book) {
+				return fmt.Errorf("%s is not one of the books available here (%s)", bibleBooks[book].Name, strings.Join(c.Books, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// effectiveConfig is what -print-config reports: the settings in effect
+// after merging defaults, the config file, the environment and flags.
+type effectiveConfig struct {
+	Flags       map[string]string `json:"flags"`
+	ConfigFile  string            `json:"config_file"`
+	Config      Config            `json:"config"`
+	Environment map[string]string `json:"environment"`
+}
+
+// configEnvironment lists the environment variables that affect the tool.
+var configEnvironment = []string{
+	"ESV_TOKEN", "BIBLE_CLI_CONFIG", "NO_COLOR", "XDG_STATE_HOME", "XDG_CACHE_HOME", "LC_ALL", "LC_MESSAGES", "LANG",
+}
+
+// runPrintConfig implements -print-config, printing the effective settings
+// with the API token redacted. translation is the -translation flag, which
+// overrides the config file. It returns the exit code.
+func runPrintConfig(opts DisplayOptions, translation string) int {
+	config, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	// Show the flag override and the defaults that apply to unset entries
+	config.Translation = cmp.Or(strings.ToUpper(translation), config.Translation, defaultTranslation)
+	copyrightVerses, maxSpanVerses := config.copyrightVerses(), config.maxSpanVerses()
+	config.CopyrightVerses, config.MaxSpanVerses = &copyrightVerses, &maxSpanVerses
+
+	effective := effectiveConfig{Flags: make(map[string]string), Config: config, Environment: make(map[string]string)}
+	flag.VisitAll(func(f *flag.Flag) {
+		effective.Flags[f.Name] = f.Value.String()
+	})
+	if path, err := configPath(); err == nil {
+		effective.ConfigFile = path
+	}
+	for _, name := range configEnvironment {
+		value, ok := os.LookupEnv(name)
+		if ok && name == "ESV_TOKEN" {
+			value = "(redacted)"
+		}
+		if ok {
+			effective.Environment[name] = value
+		}
+	}
+
+	if opts.Format == formatJSON {
+		return printJSON(effective, opts.CompactJSON)
+	}
+	fmt.Fprintln(stdout, "Flags:")
+	flag.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(stdout, "  %s = %s\n", f.Name, f.Value)
+	})
+	fmt.Fprintf(stdout, "Config file: %s\n", effective.ConfigFile)
+	fmt.Fprintf(stdout, "  translation = %s\n", config.Translation)
+	for _, key := range slices.Sorted(maps.Keys(config.Translations)) {
+		fmt.Fprintf(stdout, "  translations.%s = %s\n", key, config.Translations[key])
+	}
+	fmt.Fprintf(stdout, "  copyright_verses = %d\n", copyrightVerses)
+	fmt.Fprintf(stdout, "  max_span_verses = %d\n", maxSpanVerses)
+	if len(config.Books) > 0 {
+		fmt.Fprintf(stdout, "  books = %s\n", strings.Join(config.Books, ", "))
+	}
+	fmt.Fprintln(stdout, "Environment:")
+	for _, name := range configEnvironment {
+		if value, ok := effective.Environment[name]; ok {
+			fmt.Fprintf(stdout, "  %s = %s\n", name, value)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunPrintConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := `{"translation": "WEB", "translations": {"Psalms": "KJV", "NT": "ASV", "OT": "YLT", "John": "BBE"}}`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BIBLE_CLI_CONFIG", path)
+
+	tests := []struct {
+		translation string
+		want        string
+	}{
+		{"", "  translation = WEB\n"},
+		{"kjv", "  translation = KJV\n"},
+	}
+	for _, tt := range tests {
+		var code int
+		out := captureOutput(t, func() { code = runPrintConfig(DisplayOptions{}, tt.translation) })
+		if code != 0 {
+			t.Fatalf("runPrintConfig(%q) = %d, want 0", tt.translation, code)
+		}
+		if !strings.Contains(out, tt.want) {
+			t.Errorf("runPrintConfig(%q) output missing %q:\n%s", tt.translation, tt.want, out)
+		}
+		wantTranslations := "  translations.John = BBE\n  translations.NT = ASV\n  translations.OT = YLT\n  translations.Psalms = KJV\n"
+		if !strings.Contains(out, wantTranslations) {
+			t.Errorf("runPrintConfig(%q) translations not in sorted order:\n%s", tt.translation, out)
+		}
+	}
+}
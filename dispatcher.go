@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// defaultTranslation is used when neither the -translation flag nor the
+// config file picks one.
+const defaultTranslation = "ESV"
+
+// knownTranslations lists abbreviations of widely used translations, which
+// are recognized at the end of a reference even when no provider serves
+// them.
+var knownTranslations = []string{
+	"AMP", "ASV", "CSB", "ESV", "KJV", "MSG", "NASB", "NET", "NIV", "NKJV", "NLT", "NRSV", "RSV", "WEB", "YLT",
+}
+
+// Dispatcher fetches each reference from the provider serving the
+// translation chosen for it.
+type Dispatcher struct {
+	providers map[string]Provider // Keyed by translation abbreviation
+	config    Config
+	override  string // From -translation; wins over the config
+	resolver  *referenceResolver
+}
+
+func NewDispatcher(providers map[string]Provider, config Config, override string, resolver *referenceResolver) *Dispatcher {
+	return &Dispatcher{
+		providers: providers,
+		config:    config,
+		override:  strings.ToUpper(override),
+		resolver:  resolver,
+	}
+}
+
+// translationFor picks the translation for reference. The -translation flag
+// wins, then the config entry for the reference's book or testament, then
+// the configured default.
+func (d *Dispatcher) translationFor(reference string) string {
+	if d.override != "" {
+		return d.override
+	}
+	if key, ok := parseReference(reference); ok {
+		if translation := d.config.bookTranslation(key.Book); translation != "" {
+			return translation
+		}
+	}
+	if d.config.Translation != "" {
+		return strings.ToUpper(d.config.Translation)
+	}
+	return defaultTranslation
+}
+
+// mixedTranslations reports whether references may be fetched in more than
+// one translation, as the config can pick a different one per book.
+func (d *Dispatcher) mixedTranslations() bool {
+	if d.override != "" {
+		return false
+	}
+	used := map[string]bool{d.translationFor(""): true}
+	for _, translation := range d.config.Translations {
+		used[strings.ToUpper(translation)] = true
+	}
+	return len(used) > 1
+}
+
+// translations lists the available translations in alphabetical order.
+func (d *Dispatcher) translations() []string {
+	var names []string
+	for name := range d.providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// splitTranslation splits a trailing translation off reference, as in
+// "John 3:16 ESV" or "John 3:16 (esv)". To leave names like "Job" alone, only
+// known translations count, and only after a chapter or verse.
+func (d *Dispatcher) splitTranslation(reference string) (string, string, bool) {
+	fields := strings.Fields(reference)
+	if len(fields) < 3 {
+		return reference, "", false
+	}
+	last := strings.ToUpper(strings.Trim(fields[len(fields)-1], "()"))
+	if !slices.Contains(knownTranslations, last) && d.providers[last] == nil {
+		return reference, "", false
+	}
+	rest := strings.Join(fields[:len(fields)-1], " ")
+	if _, location, ok := splitReference(rest); !ok || location == "" {
+		return reference, "", false
+	}
+	return rest, last, true
+}
+
+// provider returns the provider serving translation.
+func (d *Dispatcher) provider(translation string) (Provider, error) {
+	provider, ok := d.providers[translation]
+	if !ok {
+		return nil, fmt.Errorf("translation %s is not available (available: %s)", translation, strings.Join(d.translations(), ", "))
+	}
+	return provider, nil
+}
+
+// FetchVerse fetches reference, or the canonical reference it resolved to
+// before, in the translation chosen for it: one given at the end of the
+// reference, otherwise the one translationFor picks.
+func (d *Dispatcher) FetchVerse(reference string) (*ESVResponse, error) {
+	input, translation, ok := d.splitTranslation(reference)
+	query := input
+	if resolved, found := d.resolver.Resolve(input); found {
+		debugLog.Printf("resolved %q to %q", input, resolved)
+		query = resolved
+	}
+	if !ok {
+		translation = d.translationFor(query)
+	}
+	if err := d.config.checkBooks(query); err != nil {
+		return nil, err
+	}
+	provider, err := d.provider(translation)
+	if err != nil {
+		return nil, err
+	}
+
+	verse, err := provider.FetchVerse(query)
+	if err != nil {
+		return nil, err
+	}
+	verse.Translation = translation
+	verse.Query = reference
+	if len(verse.Passages) > 0 && verse.Canonical != "" {
+		d.resolver.Remember(input, verse.Canonical)
+	}
+	return verse, nil
+}
+
+// WaitForRefreshes blocks until the background cache refreshes of all
+// providers have finished.
+func (d *Dispatcher) WaitForRefreshes() {
+	for _, provider := range d.providers {
+		provider.WaitForRefreshes()
+	}
+}
+
+// selectionLog explains the choice of random and daily verses on stderr when
+// -explain-selection is given.
+var selectionLog = log.New(io.Discard, "selection: ", 0)
+
+// GetRandomVerse fetches a verse picked at random from pool.
+func (d *Dispatcher) GetRandomVerse(pool []string) (*ESVResponse, error) {
+	return d.randomVerse(pool, rand.Intn, "at random")
+}
+
+// GetSeededVerse fetches the verse of pool chosen by seed, so equal seeds
+// always yield the same verse.
+func (d *Dispatcher) GetSeededVerse(seed int64, pool []string) (*ESVResponse, error) {
+	rng := rand.New(rand.NewSource(seed))
+	return d.randomVerse(pool, rng.Intn, fmt.Sprintf("with seed %d", seed))
+}
+
+// randomVerse fetches the verse of pool that the provider of the default
+// translation picks with intn. how tells selectionLog how it was picked.
+func (d *Dispatcher) randomVerse(pool []string, intn func(n int) int, how string) (*ESVResponse, error) {
+	provider, err := d.provider(d.translationFor(""))
+	if err != nil {
+		return nil, err
+	}
+	reference, err := provider.Random(pool, intn)
+	if err != nil {
+		return nil, err
+	}
+	selectionLog.Printf("picked %s, number %d of the %d in the pool, %s", reference, slices.Index(pool, reference)+1, len(pool), how)
+	return d.FetchVerse(reference)
+}
+
+// Search finds passages containing words in the translation given by
+// -translation or the config.
+func (d *Dispatcher) Search(words string, limit int) ([]SearchResult, error) {
+	provider, err := d.provider(d.translationFor(""))
+	if err != nil {
+		return nil, err
+	}
+	return provider.Search(words, limit)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeProvider serves passages whose text names the provider, recording
+// what was asked of it.
+type fakeProvider struct {
+	name     string
+	fetched  []string
+	searched []string
+}
+
+func (p *fakeProvider) FetchVerse(reference string) (*ESVResponse, error) {
+	p.fetched = append(p.fetched, reference)
+	if strings.HasPrefix(reference, "Nowhere") {
+		return &ESVResponse{Query: reference}, nil
+	}
+	canonical := normalizeReference(reference)
+	return &ESVResponse{Canonical: canonical, Passages: []string{p.name + ": " + canonical}}, nil
+}
+
+func (p *fakeProvider) Search(words string, limit int) ([]SearchResult, error) {
+	p.searched = append(p.searched, words)
+	return []SearchResult{{Reference: "John 4:14", Content: p.name + ": " + words}}, nil
+}
+
+func (p *fakeProvider) Random(pool []string, intn func(n int) int) (string, error) {
+	return pickFromPool(pool, intn)
+}
+
+func (p *fakeProvider) WaitForRefreshes() {}
+
+// newFakeDispatcher returns a Dispatcher over fake providers for the ESV,
+// KJV and WEB.
+func newFakeDispatcher(config Config, override string) (*Dispatcher, map[string]*fakeProvider) {
+	fakes := make(map[string]*fakeProvider)
+	providers := make(map[string]Provider)
+	for _, name := range []string{"ESV", "KJV", "WEB"} {
+		fakes[name] = &fakeProvider{name: name}
+		providers[name] = fakes[name]
+	}
+	return NewDispatcher(providers, config, override, newReferenceResolver(nopCache{})), fakes
+}
+
+func TestDispatcherFetchVerse(t *testing.T) {
+	config := Config{Translation: "kjv", Translations: map[string]string{"NT": "web", "Psalms": "esv"}}
+	tests := []struct {
+		override        string
+		reference       string
+		wantTranslation string
+		wantFetched     string
+		wantErr         string
+	}{
+		{"", "Genesis 1:1", "KJV", "Genesis 1:1", ""},
+		{"", "John 3:16", "WEB", "John 3:16", ""},
+		{"", "Psalm 23", "ESV", "Psalm 23", ""},
+		{"", "John 3:16 (kjv)", "KJV", "John 3:16", ""},
+		{"", "John 3:16 ESV", "ESV", "John 3:16", ""},
+		{"esv", "John 3:16", "ESV", "John 3:16", ""},
+		{"esv", "Job 1:1 WEB", "WEB", "Job 1:1", ""},
+		{"", "John 3:16 NIV", "", "", "translation NIV is not available (available: ESV, KJV, WEB)"},
+		{"NIV", "John 3:16", "", "", "translation NIV is not available"},
+	}
+	for _, tt := range tests {
+		d, fakes := newFakeDispatcher(config, tt.override)
+		verse, err := d.FetchVerse(tt.reference)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("FetchVerse(%q) error = %v, want %q", tt.reference, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("FetchVerse(%q) error = %v", tt.reference, err)
+			continue
+		}
+		if verse.Translation != tt.wantTranslation || verse.Query != tt.reference {
+			t.Errorf("FetchVerse(%q) = translation %q, query %q, want %q, %q", tt.reference, verse.Translation, verse.Query, tt.wantTranslation, tt.reference)
+		}
+		for name, fake := range fakes {
+			var want []string
+			if name == tt.wantTranslation {
+				want = []string{tt.wantFetched}
+			}
+			if !slices.Equal(fake.fetched, want) {
+				t.Errorf("FetchVerse(%q) asked %s for %q, want %q", tt.reference, name, fake.fetched, want)
+			}
+		}
+	}
+}
+
+func TestDispatcherResolvesReferences(t *testing.T) {
+	d, fakes := newFakeDispatcher(Config{}, "")
+	for _, reference := range []string{"jn 3:16", "JN  3:16", "Nowhere 1:1", "Nowhere 1:1"} {
+		if _, err := d.FetchVerse(reference); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A reference found once is asked for by its canonical name after
+	want := []string{"jn 3:16", "John 3:16", "Nowhere 1:1", "Nowhere 1:1"}
+	if got := fakes["ESV"].fetched; !slices.Equal(got, want) {
+		t.Errorf("fetched %q, want %q", got, want)
+	}
+}
+
+func TestDispatcherBooks(t *testing.T) {
+	d, fakes := newFakeDispatcher(Config{Books: []string{"NT"}}, "")
+	if _, err := d.FetchVerse("Genesis 1:1"); err == nil {
+		t.Error("FetchVerse of a book the config excludes succeeded, want an error")
+	}
+	if len(fakes["ESV"].fetched) > 0 {
+		t.Errorf("fetched %q for an excluded book", fakes["ESV"].fetched)
+	}
+}
+
+func TestDispatcherSeededVerse(t *testing.T) {
+	pool := []string{"John 3:16", "Romans 8:28", "Psalm 23:1", "Isaiah 40:31", "Philippians 4:13"}
+	d, fakes := newFakeDispatcher(Config{Translations: map[string]string{"OT": "KJV"}}, "")
+
+	first, err := d.GetSeededVerse(42, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		verse, err := d.GetSeededVerse(42, pool)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if verse.Canonical != first.Canonical {
+			t.Errorf("GetSeededVerse(42) = %q, then %q, want the same verse", first.Canonical, verse.Canonical)
+		}
+	}
+	if !slices.Contains(pool, first.Canonical) {
+		t.Errorf("GetSeededVerse(42) = %q, which isn't in the pool", first.Canonical)
+	}
+
+	// The picked verse is fetched in the translation chosen for its book
+	key, _ := parseReference(first.Canonical)
+	want := "ESV"
+	if bookTestament(key.Book) == testamentOld {
+		want = "KJV"
+	}
+	if first.Translation != want || len(fakes[want].fetched) != 6 {
+		t.Errorf("GetSeededVerse(42) fetched %q in %s, want %s", first.Canonical, first.Translation, want)
+	}
+}
+
+func TestDispatcherRandomVerseEmptyPool(t *testing.T) {
+	d, _ := newFakeDispatcher(Config{}, "")
+	if _, err := d.GetRandomVerse(nil); err == nil {
+		t.Error("GetRandomVerse of an empty pool succeeded, want an error")
+	}
+}
+
+func TestDispatcherSearch(t *testing.T) {
+	tests := []struct {
+		config   Config
+		override string
+		want     string
+	}{
+		{Config{}, "", "ESV"},
+		{Config{Translation: "web"}, "", "WEB"},
+		{Config{Translation: "web"}, "kjv", "KJV"},
+	}
+	for _, tt := range tests {
+		d, fakes := newFakeDispatcher(tt.config, tt.override)
+		results, err := d.Search("living water", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].Content != tt.want+": living water" {
+			t.Errorf("Search with translation %q, -translation %q = %v, want a result from %s", tt.config.Translation, tt.override, results, tt.want)
+		}
+		if got := fakes[tt.want].searched; !slices.Equal(got, []string{"living water"}) {
+			t.Errorf("%s searched for %q, want [\"living water\"]", tt.want, got)
+		}
+	}
+}
+
+func TestPickFromPool(t *testing.T) {
+	pool := []string{"John 3:16", "Romans 8:28"}
+	for i, want := range pool {
+		got, err := pickFromPool(pool, func(n int) int { return i })
+		if got != want || err != nil {
+			t.Errorf("pickFromPool(%d) = %q, %v, want %q, nil", i, got, err, want)
+		}
+	}
+	if _, err := pickFromPool(nil, func(n int) int { return 0 }); err == nil {
+		t.Error("pickFromPool(nil) succeeded, want an error")
+	}
+}
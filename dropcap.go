@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// dropCapFont holds a 5x5 pixel bitmap for each capital letter. The bitmaps
+// are drawn with half-block characters, two pixel rows per terminal line.
+var dropCapFont = map[rune][5]string{
+	'A': {".###.", "#...#", "#####", "#...#", "#...#"},
+	'B': {"####.", "#...#", "####.", "#...#", "####."},
+	'C': {".####", "#....", "#....", "#....", ".####"},
+	'D': {"####.", "#...#", "#...#", "#...#", "####."},
+	'E': {"#####", "#....", "####.", "#....", "#####"},
+	'F': {"#####", "#....", "####.", "#....", "#...."},
+	'G': {".####", "#....", "#.###", "#...#", ".###."},
+	'H': {"#...#", "#...#", "#####", "#...#", "#...#"},
+	'I': {"#####", "..#..", "..#..", "..#..", "#####"},
+	'J': {"..###", "...#.", "...#.", "#..#.", ".##.."},
+	'K': {"#...#", "#..#.", "###..", "#..#.", "#...#"},
+	'L': {"#....", "#....", "#....", "#....", "#####"},
+	'M': {"#...#", "##.##", "#.#.#", "#...#", "#...#"},
+	'N': {"#...#", "##..#", "#.#.#", "#..##", "#...#"},
+	'O': {".###.", "#...#", "#...#", "#...#", ".###."},
+	'P': {"####.", "#...#", "####.", "#....", "#...."},
+	'Q': {".###.", "#...#", "#.#.#", "#..#.", ".##.#"},
+	'R': {"####.", "#...#", "####.", "#..#.", "#...#"},
+	'S': {".####", "#....", ".###.", "....#", "####."},
+	'T': {"#####", "..#..", "..#..", "..#..", "..#.."},
+	'U': {"#...#", "#...#", "#...#", "#...#", ".###."},
+	'V': {"#...#", "#...#", "#...#", ".#.#.", "..#.."},
+	'W': {"#...#", "#...#", "#.#.#", "##.##", "#...#"},
+	'X': {"#...#", ".#.#.", "..#..", ".#.#.", "#...#"},
+	'Y': {"#...#", ".#.#.", "..#..", "..#..", "..#.."},
+	'Z': {"#####", "...#.", "..#..", ".#...", "#####"},
+}
+
+const (
+	dropCapWidth  = 5
+	dropCapHeight = 3 // Terminal lines, i.e. 5 pixel rows rounded up
+)
+
+// dropCapLines renders letter as dropCapHeight lines of half-block
+// characters, or returns false when the font has no glyph for it.
+func dropCapLines(letter rune) ([]string, bool) {
+	bitmap, ok := dropCapFont[unicode.ToUpper(letter)]
+	if !ok {
+		return nil, false
+	}
+
+	lines := make([]string, dropCapHeight)
+	for i := range lines {
+		var b strings.Builder
+		for col := 0; col < dropCapWidth; col++ {
+			top := bitmap[2*i][col] == '#'
+			bottom := 2*i+1 < len(bitmap) && bitmap[2*i+1][col] == '#'
+			switch {
+			case top && bottom:
+				b.WriteRune('█')
+			case top:
+				b.WriteRune('▀')
+			case bottom:
+				b.WriteRune('▄')
+			default:
+				b.WriteRune(' ')
+			}
+		}
+		lines[i] = b.String()
+	}
+	return lines, true
+}
+
+// printDropCap prints to w the first line of the passage with its first letter
+// enlarged, wrapping the text around the letter, and returns the lines that
+// are still to be printed. Passages that don't start with a letter the font
+// covers are returned unchanged.
+func printDropCap(w io.Writer, lines []string, maxWidth int) []string {
+	if len(lines) == 0 {
+		return lines
+	}
+	first := foldWhitespace(lines[0], false)
+	letter := []rune(first + " ")[0]
+	capLines, ok := dropCapLines(letter)
+	if !ok {
+		return lines
+	}
+
+	rest := first[len(string(letter)):]
+	wrapped := wrapWidths(rest, func(i int) int {
+		if i < dropCapHeight {
+			return maxWidth - dropCapWidth - 1
+		}
+		return maxWidth
+	})
+
+	for i, capLine := range capLines {
+		text := ""
+		if i < len(wrapped) {
+			text = wrapped[i]
+		}
+		fmt.Fprintf(w, " %s %s\n", capLine, text)
+	}
+	for i := dropCapHeight; i < len(wrapped); i++ {
+		fmt.Fprintf(w, " %s\n", wrapped[i])
+	}
+	return lines[1:]
+}
+
+// wrapWidths wraps text like wrapText, but lets the width vary per line.
+// The first word always stays on the first line, attached to the start of
+// the text, so a drop cap reads as part of it.
+func wrapWidths(text string, widthFor func(line int) int) []string {
+	return wrapTokens(splitTokens(text), widthFor)
+}
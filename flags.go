@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// paramKeyPattern matches query parameter names that need no escaping.
+var paramKeyPattern = regexp.MustCompile(`^[A-Za-z0-9._~-]+$`)
+
+// paramFlag collects repeated -param key=value flags into query parameters.
+type paramFlag url.Values
+
+func (p paramFlag) String() string {
+	return url.Values(p).Encode()
+}
+
+func (p paramFlag) Set(value string) error {
+	key, val, found := strings.Cut(value, "=")
+	if !found {
+		return fmt.Errorf("want key=value, got %q", value)
+	}
+	if !paramKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid parameter name %q (letters, digits, '.', '_', '~' and '-' only)", key)
+	}
+	if key == "q" {
+		return fmt.Errorf("the q parameter is set from the reference")
+	}
+	if strings.ContainsFunc(val, unicode.IsControl) {
+		return fmt.Errorf("parameter %q contains control characters", key)
+	}
+	url.Values(p).Add(key, val)
+	return nil
+}
+
+// usage prints the synopsis and the flags of the program.
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] [reference | today | random | present | search | books | topics | streak | bookmarks | pack | ping]\n\n", flag.CommandLine.Name())
+	fmt.Fprintln(out, "Flags may come before or after the reference. Use -- to end the flags.")
+	fmt.Fprintln(out)
+	flag.PrintDefaults()
+}
+
+// parseArgs parses the flags in args, which unlike flag.Parse may be mixed
+// with positional arguments, and returns the positional arguments. Parsing
+// stops at "--" and at subcommands with flags of their own, whose remaining
+// arguments are returned unparsed.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := flags.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if endsFlags(flags, args[:len(args)-len(rest)]) {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		if positional[0] == "books" || positional[0] == "bookmarks" || positional[0] == "pack" {
+			return append(positional, rest[1:]...), nil
+		}
+		args = rest[1:]
+	}
+}
+
+// endsFlags reports whether the flags flags.Parse consumed from parsed end
+// with the "--" terminator, rather than with "--" as the value of a flag as
+// in "-param --".
+func endsFlags(flags *flag.FlagSet, parsed []string) bool {
+	for i := 0; i < len(parsed); i++ {
+		arg := parsed[i]
+		if arg == "--" {
+			return true
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		if f := flags.Lookup(name); f != nil {
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+				i++ // The value is the next argument
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args      []string
+		want      []string // nil for an error
+		wantPlain bool
+	}{
+		{[]string{"John", "3:16"}, []string{"John", "3:16"}, false},
+		{[]string{"-plain", "John", "3:16"}, []string{"John", "3:16"}, true},
+		{[]string{"John", "-plain", "3:16"}, []string{"John", "3:16"}, true},
+		{[]string{"John", "3:16", "-plain"}, []string{"John", "3:16"}, true},
+		{[]string{"--", "-plain", "John"}, []string{"-plain", "John"}, false},
+		{[]string{"John", "--", "-plain"}, []string{"John", "-plain"}, false},
+		{[]string{"-plain", "--", "-3:16"}, []string{"-3:16"}, true},
+		{[]string{"-param", "a=--", "John"}, []string{"John"}, false},
+		{[]string{"-param", "--", "-plain"}, nil, false}, // Not key=value
+		{[]string{"-note", "--", "-plain", "John"}, []string{"John"}, true},
+		{[]string{"-note", "--", "John", "-plain"}, []string{"John"}, true},
+		{[]string{"-note", "--", "--", "-plain"}, []string{"-plain"}, false},
+		{[]string{"-note=--", "John", "-plain"}, []string{"John"}, true},
+		{[]string{"--note", "--", "John", "--", "-plain"}, []string{"John", "-plain"}, false},
+		{[]string{"-plain=true", "--", "-note"}, []string{"-note"}, true},
+		{[]string{"books", "-plain"}, []string{"books", "-plain"}, false},
+	}
+	for _, tt := range tests {
+		flags := flag.NewFlagSet("bible-cli", flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		plain := flags.Bool("plain", false, "")
+		flags.String("note", "", "")
+		flags.Var(paramFlag{}, "param", "")
+
+		got, err := parseArgs(flags, tt.args)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("parseArgs(%q) = %q, want an error", tt.args, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseArgs(%q) error = %v", tt.args, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) || *plain != tt.wantPlain {
+			t.Errorf("parseArgs(%q) = %q, -plain %v, want %q, -plain %v", tt.args, got, *plain, tt.want, tt.wantPlain)
+		}
+	}
+}
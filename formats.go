@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Output formats.
+const (
+	formatBox   = "box"   // Passage in a box sized to the terminal
+	formatPlain = "plain" // Undecorated text
+	formatJSON  = "json"  // The API response as JSON
+	formatCSV   = "csv"   // Rows of reference, translation and text (RFC 4180)
+	formatTSV   = "tsv"   // Like csv, separated by tabs
+
+	formatMarkdown = "markdown" // Block quotes with citations
+)
+
+var outputFormats = []string{formatBox, formatPlain, formatJSON, formatCSV, formatTSV, formatMarkdown}
+
+// newTableWriter returns a writer for the csv or tsv format that has
+// already written the header row, with a column for the fetch time if
+// withDate is set.
+func newTableWriter(format string, withDate bool) *csv.Writer {
+	w := csv.NewWriter(stdout)
+	if format == formatTSV {
+		w.Comma = '\t'
+	} else {
+		// RFC 4180 ends records, and lines within quoted fields, with CRLF
+		w.UseCRLF = true
+	}
+	header := []string{"reference", "translation", "text"}
+	if withDate {
+		header = append(header, "fetched")
+	}
+	w.Write(header)
+	return w
+}
+
+// displayTable writes one row per passage. Lines of poetry stay separate
+// lines within the quoted text field.
+func displayTable(verse *ESVResponse, opts DisplayOptions) {
+	for i, passage := range verse.Passages {
+		var lines []string
+		for _, line := range strings.Split(cleanPassage(passage, opts), "\n") {
+			lines = append(lines, foldWhitespace(line, opts.Whitespace == whitespaceIndent))
+		}
+		row := []string{passageReference(verse, i), verse.Translation, strings.Join(lines, "\n")}
+		if opts.WithDate {
+			row = append(row, verse.Fetched.Format(time.RFC3339))
+		}
+		opts.Table.Write(row)
+	}
+	opts.Table.Flush()
+	if err := opts.Table.Error(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: writing %s: %v\n", opts.Format, err)
+	}
+}
+
+// responseFields returns the names of the fields of an ESVResponse in JSON
+// output, in order.
+func responseFields() []string {
+	var fields []string
+	t := reflect.TypeFor[ESVResponse]()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// parseFields parses the comma-separated field names given to -fields,
+// rejecting names that aren't fields of the JSON output.
+func parseFields(list string) ([]string, error) {
+	known := responseFields()
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !slices.Contains(known, field) {
+			return nil, fmt.Errorf("unknown field %q (want %s)", field, strings.Join(known, ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// selectFields returns verse reduced to the given JSON fields, for encoding
+// as JSON.
+func selectFields(verse *ESVResponse, fields []string) (any, error) {
+	data, err := json.Marshal(verse)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected, nil
+}
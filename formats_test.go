@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestDisplayTable(t *testing.T) {
+	const poetry = `{
+	"canonical": "Psalm 23:1",
+	"passages": ["The LORD is my shepherd;\n    I shall not want."]
+}`
+	tests := []struct {
+		format string
+		body   string
+		want   string
+	}{
+		{
+			formatCSV, twoPassageResponse,
+			"reference,translation,text\r\n" +
+				"John 3:16,ESV,For God so loved the world\r\n" +
+				"Romans 8:28,ESV,And we know that for those who love God\r\n",
+		},
+		{
+			formatCSV, poetry,
+			"reference,translation,text\r\n" +
+				"Psalm 23:1,ESV,\"The LORD is my shepherd;\r\nI shall not want.\"\r\n",
+		},
+		{
+			formatTSV, twoPassageResponse,
+			"reference\ttranslation\ttext\n" +
+				"John 3:16\tESV\tFor God so loved the world\n" +
+				"Romans 8:28\tESV\tAnd we know that for those who love God\n",
+		},
+	}
+	for _, tt := range tests {
+		verse := parseResponse(t, tt.body)
+		verse.Translation = "ESV"
+		got := captureOutput(t, func() {
+			displayTable(verse, DisplayOptions{Format: tt.format, Table: newTableWriter(tt.format, false)})
+		})
+		if got != tt.want {
+			t.Errorf("displayTable(%s of %s) = %q, want %q", tt.format, verse.Canonical, got, tt.want)
+		}
+	}
+}
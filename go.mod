@@ -3,6 +3,8 @@ module github.com/alex-kattathra-johnson/bible-cli
 go 1.24.5
 
 require (
-	golang.org/x/sys v0.35.0 // indirect
-	golang.org/x/term v0.34.0 // indirect
+	golang.org/x/term v0.34.0
+	golang.org/x/text v0.28.0
 )
+
+require golang.org/x/sys v0.35.0 // indirect
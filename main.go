@@ -1,46 +1,95 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
 	_ "embed"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"hash/fnv"
 	"io"
-	"math/rand"
+	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"golang.org/x/term"
 )
 
 const (
-	apiBaseURL = "https://api.esv.org/v3/passage/text/"
+	apiBaseURL   = "https://api.esv.org/v3/passage/text/"
+	apiHTMLURL   = "https://api.esv.org/v3/passage/html/"
+	apiSearchURL = "https://api.esv.org/v3/passage/search/"
+
+	// maxReferenceLength bounds the reference sent to the API. Even long lists
+	// of references fit comfortably in it.
+	maxReferenceLength = 1000
 )
 
 //go:embed verses.json
 var versesJSON []byte
 
 type VersesData struct {
-	Verses []string `json:"verses"`
+	Verses []string            `json:"verses"`
+	Topics map[string][]string `json:"topics"` // Tag to references from Verses
 }
 
 var bibleVerses []string
 
+// verseTopics maps each topic tag to the references in bibleVerses about it.
+var verseTopics map[string][]string
+
+// debugLog reports diagnostics on stderr when -debug is given.
+var debugLog = log.New(io.Discard, "debug: ", 0)
+
 func init() {
-	var data VersesData
-	if err := json.Unmarshal(versesJSON, &data); err != nil {
+	data, err := parseVerses(versesJSON)
+	if err != nil {
 		panic(fmt.Sprintf("Failed to load verses: %v", err))
 	}
 	bibleVerses = data.Verses
+	verseTopics = data.Topics
+}
+
+// parseVerses decodes the built-in verses. An empty file, e.g. in a fork,
+// yields no verses, which only disables random verses.
+func parseVerses(raw []byte) (VersesData, error) {
+	var data VersesData
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return data, nil
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return data, err
+	}
+	for topic, references := range data.Topics {
+		for _, reference := range references {
+			if !slices.Contains(data.Verses, reference) {
+				return data, fmt.Errorf("topic %q lists unknown verse %q", topic, reference)
+			}
+		}
+	}
+	return data, nil
 }
 
 type ESVResponse struct {
-	Query       string   `json:"query"`
-	Canonical   string   `json:"canonical"`
-	Parsed      [][]int  `json:"parsed"`
-	Passages    []string `json:"passages"`
+	Query       string    `json:"query"`
+	Translation string    `json:"translation,omitempty"` // Set by Dispatcher, not the API
+	Canonical   string    `json:"canonical"`
+	Parsed      [][]int   `json:"parsed"`
+	Passages    []string  `json:"passages"`
+	HTML        []string  `json:"html,omitempty"`        // The passages as HTML, with -with-html
+	Suggestions []string  `json:"suggestions,omitempty"` // Offered when no passage matched
+	Stale       bool      `json:"-"`                     // Served from an expired cache entry
+	Fetched     time.Time `json:"fetched,omitzero"`      // Set with -with-date
 	PassageMeta []struct {
 		Canonical    string `json:"canonical"`
 		ChapterStart []int  `json:"chapter_start"`
@@ -50,39 +99,252 @@ type ESVResponse struct {
 	} `json:"passage_meta"`
 }
 
+// FetchOptions controls which optional parts of a passage the API returns.
+type FetchOptions struct {
+	PoetryLines  bool       // Keep the line breaks and indentation of poetic passages
+	Selahs       bool       // Include "Selah" notations
+	VerseNumbers bool       // Include verse number markers like "[16]"
+	ExtraParams  url.Values // Added to the query, replacing defaults of the same name
+	RawResponse  io.Writer  // If set, receives every response body exactly as read
+	HTML         bool       // Also fetch the passages as HTML
+	Retry        retryPolicy
+	Limiter      *adaptiveLimiter // If set, bounds concurrent requests
+	Cache        responseCache    // Defaults to nopCache
+
+	// MaxSpanVerses rejects queries spanning more verses before asking the
+	// API. Zero allows any length.
+	MaxSpanVerses int
+
+	// CopyrightVerses is the passage length, in verses, above which the
+	// short copyright notice is kept. Negative never requests the notice.
+	CopyrightVerses int
+
+	// StaleTimeout enables stale-while-revalidate: if a live fetch takes
+	// longer, an expired cached response is served while the fetch goes on
+	// in the background to refresh the cache. Zero always waits.
+	StaleTimeout time.Duration
+}
+
 type BibleClient struct {
-	apiKey string
-	client *http.Client
+	apiKey    string
+	baseURL   string
+	htmlURL   string
+	searchURL string
+	client    *http.Client
+	options   FetchOptions
+	refreshes sync.WaitGroup // Background cache refreshes
 }
 
-func NewBibleClient(apiKey string) *BibleClient {
+func NewBibleClient(apiKey string, options FetchOptions) *BibleClient {
+	if options.Cache == nil {
+		options.Cache = nopCache{}
+	}
 	return &BibleClient{
-		apiKey: apiKey,
+		apiKey:    apiKey,
+		baseURL:   apiBaseURL,
+		htmlURL:   apiHTMLURL,
+		searchURL: apiSearchURL,
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		options: options,
 	}
 }
 
+// shortCopyright is the notice the API appends to passages with
+// include-short-copyright.
+const shortCopyright = "(ESV)"
+
+// validateReference rejects references the API can't reasonably serve,
+// including ones spanning more than maxSpan verses unless maxSpan is zero.
+func validateReference(reference string, maxSpan int) error {
+	if len(reference) > maxReferenceLength {
+		return fmt.Errorf("reference is too long (%d bytes, maximum %d)", len(reference), maxReferenceLength)
+	}
+	if maxSpan <= 0 {
+		return nil
+	}
+	if verses, ok := queryVerses(reference); ok && verses > maxSpan {
+		first, _, _ := strings.Cut(reference, ";")
+		start, _, _ := referenceSpan(first)
+		return fmt.Errorf("%q spans %d verses, more than the maximum of %d; try a smaller range, e.g. %s %d",
+			reference, verses, maxSpan, bibleBooks[start.Book].Name, start.Chapter)
+	}
+	return nil
+}
+
 func (bc *BibleClient) FetchVerse(reference string) (*ESVResponse, error) {
+	if err := validateReference(reference, bc.options.MaxSpanVerses); err != nil {
+		return nil, err
+	}
+
+	// The notice is requested always and removed again from short passages,
+	// whose length is only known from the response
+	copyright := bc.options.CopyrightVerses >= 0
+	params := bc.passageParams(reference, copyright, true)
+	if bc.options.ExtraParams.Has("include-short-copyright") {
+		copyright = false // Left as the user asked for it
+	}
+
+	var esvResp ESVResponse
+	info, err := bc.fetchJSON(bc.baseURL, params, &esvResp)
+	if err != nil {
+		return nil, err
+	}
+	esvResp.Stale = info.Stale
+	esvResp.Fetched = info.Fetched.Truncate(time.Second)
+	if esvResp.Query == "" {
+		esvResp.Query = reference
+	}
+	short := copyright && parsedVerses(esvResp.Parsed) <= bc.options.CopyrightVerses
+	if short {
+		for i, passage := range esvResp.Passages {
+			esvResp.Passages[i] = strings.TrimSuffix(strings.TrimRight(passage, " \n"), shortCopyright)
+		}
+	}
+
+	if bc.options.HTML && len(esvResp.Passages) > 0 {
+		// The notice is decided by now, so the HTML asks for it only if the
+		// text kept it
+		params := bc.passageParams(reference, bc.options.CopyrightVerses >= 0 && !short, false)
+		var htmlResp ESVResponse
+		if _, err := bc.fetchJSON(bc.htmlURL, params, &htmlResp); err != nil {
+			return nil, fmt.Errorf("fetching HTML: %w", err)
+		}
+		esvResp.HTML = htmlResp.Passages
+	}
+
+	return &esvResp, nil
+}
+
+// passageParams returns the query parameters for reference on the text
+// endpoint, or the HTML one if text is false. -param values replace the
+// defaults.
+func (bc *BibleClient) passageParams(reference string, copyright, text bool) url.Values {
 	params := url.Values{}
 	params.Add("q", reference)
 	params.Add("include-headings", "false")
 	params.Add("include-footnotes", "false")
-	params.Add("include-verse-numbers", "false")
-	params.Add("include-short-copyright", "false")
+	params.Add("include-verse-numbers", fmt.Sprint(bc.options.VerseNumbers))
+	params.Add("include-short-copyright", fmt.Sprint(copyright))
 	params.Add("include-passage-references", "false")
-	params.Add("include-selahs", "false")       // Disable "Selah" notations
-	params.Add("include-poetry-lines", "false") // Disable poetry line markers
+	params.Add("include-selahs", fmt.Sprint(bc.options.Selahs))
+	if text {
+		params.Add("include-poetry-lines", fmt.Sprint(bc.options.PoetryLines))
+	}
+	for key, values := range bc.options.ExtraParams {
+		params[key] = values
+	}
+	return params
+}
 
-	fullURL := fmt.Sprintf("%s?%s", apiBaseURL, params.Encode())
+// responseInfo describes where a response body came from.
+type responseInfo struct {
+	Fetched time.Time // When the API sent it, which is earlier for cached bodies
+	Stale   bool      // Whether it came from an expired cache entry
+}
+
+// fetchJSON requests the endpoint at baseURL with params and decodes the
+// response into v.
+func (bc *BibleClient) fetchJSON(baseURL string, params url.Values, v any) (responseInfo, error) {
+	fullURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
+	body, info, err := bc.fetchBody(fullURL)
+	if err != nil {
+		return responseInfo{}, err
+	}
+
+	if !utf8.Valid(body) {
+		// Garbled in transit or in the cache; keep the rest readable
+		debugLog.Printf("response for %q is not valid UTF-8, replacing invalid bytes", params.Get("q"))
+		body = bytes.ToValidUTF8(body, []byte("\uFFFD"))
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return responseInfo{}, fmt.Errorf("parsing response: %w", err)
+	}
+	return info, nil
+}
+
+// fetchBody returns the response body for fullURL, from the cache when
+// possible.
+func (bc *BibleClient) fetchBody(fullURL string) ([]byte, responseInfo, error) {
+	cache := bc.options.Cache
+	live := func() ([]byte, responseInfo, error) {
+		body, err := bc.fetchLive(fullURL)
+		if err != nil {
+			return nil, responseInfo{}, err
+		}
+		cache.Put(fullURL, body)
+		return body, responseInfo{Fetched: time.Now()}, nil
+	}
+	if bc.options.RawResponse != nil {
+		// -raw-response wants what the API sends, not what it sent before
+		return live()
+	}
+	cached, found := cache.Get(fullURL)
+	if found && cached.Fresh {
+		debugLog.Printf("cache hit for %s", fullURL)
+		return cached.Body, responseInfo{Fetched: cached.Stored}, nil
+	}
+	if !found || bc.options.StaleTimeout <= 0 {
+		return live()
+	}
+
+	type result struct {
+		body []byte
+		info responseInfo
+		err  error
+	}
+	done := make(chan result, 1)
+	bc.refreshes.Add(1)
+	go func() {
+		defer bc.refreshes.Done()
+		body, info, err := live()
+		done <- result{body, info, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.body, r.info, r.err
+	case <-time.After(bc.options.StaleTimeout):
+		debugLog.Printf("serving stale cache for %s while refreshing", fullURL)
+		return cached.Body, responseInfo{Fetched: cached.Stored, Stale: true}, nil
+	}
+}
+
+// WaitForRefreshes blocks until background cache refreshes have finished.
+func (bc *BibleClient) WaitForRefreshes() {
+	bc.refreshes.Wait()
+}
+
+// fetchLive requests fullURL from the API, retrying according to the
+// client's retry policy.
+func (bc *BibleClient) fetchLive(fullURL string) ([]byte, error) {
+	var body []byte
+	err := bc.options.Retry.do(func() error {
+		var err error
+		body, err = bc.get(fullURL)
+		return err
+	})
+	return body, err
+}
 
+// get makes a single authenticated request and returns the response body.
+func (bc *BibleClient) get(fullURL string) ([]byte, error) {
 	req, err := http.NewRequest("GET", fullURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Token "+bc.apiKey)
+	if bc.apiKey != "" {
+		req.Header.Set("Authorization", "Token "+bc.apiKey)
+	}
+	debugLog.Printf("GET %s", fullURL) // The token is only in the header
+
+	if limiter := bc.options.Limiter; limiter != nil {
+		limiter.Acquire()
+		defer func() { limiter.Release(isRateLimited(err)) }()
+	}
 
 	resp, err := bc.client.Do(req)
 	if err != nil {
@@ -90,27 +352,48 @@ func (bc *BibleClient) FetchVerse(reference string) (*ESVResponse, error) {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	body, err := io.ReadAll(resp.Body)
+	if bc.options.RawResponse != nil {
+		if _, werr := bc.options.RawResponse.Write(body); werr != nil {
+			debugLog.Printf("writing raw response: %v", werr)
+		}
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		err = newAPIError(resp, body)
+		return nil, err
+	}
 	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+		// The connection broke off mid-body; the partial JSON is useless
+		return nil, fmt.Errorf("%w (got %d bytes): %v", errIncompleteResponse, len(body), err)
 	}
+	return body, nil
+}
 
-	var esvResp ESVResponse
-	if err := json.Unmarshal(body, &esvResp); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+// explainSeed tells selectionLog what the seed of a daily or hostname
+// seeded verse is made of.
+func explainSeed(day time.Time, clock, date, machineID string) {
+	source := "the " + clock + " clock"
+	if date != "" {
+		source = "-date"
 	}
-
-	return &esvResp, nil
+	if machineID != "" {
+		selectionLog.Printf("seed from %s (by %s) and the hostname %q", day.Format(dayLayout), source, machineID)
+		return
+	}
+	selectionLog.Printf("seed from %s (by %s)", day.Format(dayLayout), source)
 }
 
-func (bc *BibleClient) GetRandomVerse() (*ESVResponse, error) {
-	randomRef := bibleVerses[rand.Intn(len(bibleVerses))]
-	return bc.FetchVerse(randomRef)
+// dailySeed derives a seed from the calendar date of day, optionally mixed
+// with a machine identifier so different machines get different verses.
+func dailySeed(day time.Time, machineID string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(day.Format("2006-01-02")))
+	if machineID != "" {
+		h.Write([]byte{0})
+		h.Write([]byte(machineID))
+	}
+	return int64(h.Sum64())
 }
 
 func getTerminalWidth() int {
@@ -122,109 +405,1006 @@ func getTerminalWidth() int {
 	return width
 }
 
-func displayVerse(verse *ESVResponse) {
+// Whitespace handling modes for passage text.
+const (
+	whitespaceFold   = "fold"   // Collapse all runs of whitespace, including indentation
+	whitespaceIndent = "indent" // Collapse runs within a line but keep leading indentation
+)
+
+// Layouts for responses containing several passages.
+const (
+	passagesSeparate = "separate" // One box per passage
+	passagesCombined = "combined" // One box with a divider between passages
+)
+
+// Verse layouts within a passage
+const (
+	verseLayoutProse = "prose" // Verses flow together as the API joins them
+	verseLayoutLines = "lines" // Each verse starts on a new line
+	verseLayoutTable = "table" // Each verse is a row, its number in a gutter
+)
+
+// When to show the translation after the reference
+const (
+	showTranslationAuto   = "auto" // When the run may mix translations
+	showTranslationAlways = "always"
+	showTranslationNever  = "never"
+)
+
+// Separators between the reference and the text in the plain format
+const (
+	plainSeparatorBlank = "blank" // A blank line
+	plainSeparatorColon = "colon" // "John 3:16: For God..."
+	plainSeparatorDash  = "dash"  // "John 3:16 - For God..."
+)
+
+// quoteMarks maps the styles of -quote to their opening and closing marks.
+var quoteMarks = map[string][2]string{
+	"double":     {"“", "”"},
+	"single":     {"‘", "’"},
+	"guillemets": {"«", "»"},
+	"straight":   {`"`, `"`},
+}
+
+// DisplayOptions controls how a passage is rendered.
+type DisplayOptions struct {
+	Whitespace string
+	Passages   string
+	Format     string
+	Table      *csv.Writer       // Shared by all verses in the csv and tsv formats
+	Markdown   *markdownDocument // Shared by all verses in the markdown format
+	DropCap    bool              // Enlarge the first letter of each passage
+	Selahs     bool              // Set "Selah" apart from the text
+
+	ColorReference bool     // Color the reference by its book and chapter
+	CompactJSON    bool     // Single-line JSON
+	Fields         []string // If set, the only fields in JSON output
+
+	PlainSeparator string // One of the plain separators
+
+	// Quote, if set, is a key of quoteMarks to put around each passage.
+	Quote string
+
+	// NoBoxTop and NoBoxBottom leave out the top and bottom borders of the
+	// box, e.g. to stack boxes without doubled rules.
+	NoBoxTop    bool
+	NoBoxBottom bool
+
+	// WithDate adds when each verse was fetched to the output.
+	WithDate bool
+
+	// ShowTranslation appends the translation to the reference, e.g.
+	// "John 3:16 (ESV)".
+	ShowTranslation bool
+
+	// VerseLayout is one of the verse layouts. The lines and table layouts
+	// find verses by their numbers, which HideVerseNumbers removes again
+	// when they weren't asked for, except from the gutter of the table.
+	VerseLayout      string
+	HideVerseNumbers bool
+
+	// Copy, if set, also puts the passage on the clipboard; StripNumbers
+	// leaves verse numbers out of the copy even when they are displayed.
+	Copy         bool
+	StripNumbers bool
+}
+
+// selahPattern matches a "Selah" notation and the space before it.
+var selahPattern = regexp.MustCompile(`\s*\bSelah\b`)
+
+// displayVerse renders verse in the format of opts. Only the JSON format
+// can fail, when encoding or writing the JSON does.
+func displayVerse(verse *ESVResponse, opts DisplayOptions) error {
+	defer passageDone()
+	writePendingOutput()
 	if verse == nil || len(verse.Passages) == 0 {
-		fmt.Println("No passage found")
-		return
+		fmt.Fprintln(stdout, "No passage found")
+		return nil
+	}
+	if verse.Stale {
+		fmt.Fprintln(os.Stderr, "(cached, possibly outdated)")
+	}
+	if opts.Copy {
+		if err := copyToClipboard(clipboardText(verse, opts)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: copying to clipboard: %v\n", err)
+		}
+	}
+
+	switch opts.Format {
+	case formatJSON:
+		if !opts.WithDate {
+			withoutDate := *verse
+			withoutDate.Fetched = time.Time{}
+			verse = &withoutDate
+		}
+		if opts.Fields == nil {
+			return writeJSON(verse, opts.CompactJSON)
+		}
+		selected, err := selectFields(verse, opts.Fields)
+		if err != nil {
+			return fmt.Errorf("encoding JSON: %w", err)
+		}
+		return writeJSON(selected, opts.CompactJSON)
+	case formatPlain:
+		displayPlain(verse, opts)
+		return nil
+	case formatCSV, formatTSV:
+		displayTable(verse, opts)
+		return nil
+	case formatMarkdown:
+		displayMarkdown(verse, opts)
+		return nil
 	}
 
-	// Use the canonical reference from the API response
-	reference := verse.Canonical
-	passageText := strings.TrimSpace(verse.Passages[0])
+	// A query with several references ("John 3:16; Romans 8:28") returns one
+	// passage per reference, each with its own canonical reference
+	if opts.Passages == passagesCombined {
+		width := boxWidth()
+		printBoxTop(width, opts)
+		for i, passage := range verse.Passages {
+			if i > 0 {
+				fmt.Fprintln(stdout, strings.Repeat("┄", width))
+			}
+			printPassageSection(stdout, passageHeader(verse, i, opts), passage, width, opts)
+		}
+		printFetched(verse, width, opts)
+		printBoxBottom(width, opts)
+		return nil
+	}
 
-	// Get terminal width and calculate box width
-	termWidth := getTerminalWidth()
-	width := termWidth - 4 // Leave some margin
-	if width < 40 {
-		width = 40 // Minimum width
+	for i, passage := range verse.Passages {
+		width := boxWidth()
+		printBoxTop(width, opts)
+		printPassageSection(stdout, passageHeader(verse, i, opts), passage, width, opts)
+		printFetched(verse, width, opts)
+		printBoxBottom(width, opts)
 	}
-	if width > 120 {
-		width = 120 // Cap max width for readability
+	return nil
+}
+
+// fetchedLayout formats the fetch time in human-readable output.
+const fetchedLayout = "January 2, 2006 at 3:04 PM"
+
+// printFetched prints when verse was fetched, right-aligned at the foot of
+// the box, if opts asks for it.
+func printFetched(verse *ESVResponse, width int, opts DisplayOptions) {
+	if !opts.WithDate || verse.Fetched.IsZero() {
+		return
+	}
+	footer := "Fetched " + verse.Fetched.Format(fetchedLayout)
+	fmt.Fprintf(stdout, "%s%s\n", strings.Repeat(" ", max(width-1-displayWidth(footer), 0)), footer)
+}
+
+// passageReference returns the canonical reference of the i-th passage,
+// falling back to the reference of the whole response and then to the query
+// as it was typed.
+func passageReference(verse *ESVResponse, i int) string {
+	if i < len(verse.PassageMeta) && verse.PassageMeta[i].Canonical != "" {
+		return verse.PassageMeta[i].Canonical
+	}
+	if verse.Canonical != "" {
+		return verse.Canonical
+	}
+	return verse.Query
+}
+
+// passageHeader returns the reference shown above the i-th passage, with
+// the translation appended if opts asks for it.
+func passageHeader(verse *ESVResponse, i int, opts DisplayOptions) string {
+	reference := passageReference(verse, i)
+	if opts.ShowTranslation && verse.Translation != "" {
+		reference += " (" + verse.Translation + ")"
 	}
+	return reference
+}
 
+// printBoxTop prints the top border of a box and the blank line before it,
+// unless opts leaves them out.
+func printBoxTop(width int, opts DisplayOptions) {
+	if opts.NoBoxTop {
+		return
+	}
 	// Simple border style for better compatibility
-	fmt.Println()
-	fmt.Println(strings.Repeat("═", width))
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, strings.Repeat("═", width))
+}
+
+// printBoxBottom prints the bottom border of a box and the blank line after
+// it, unless opts leaves them out.
+func printBoxBottom(width int, opts DisplayOptions) {
+	if opts.NoBoxBottom {
+		return
+	}
+	fmt.Fprintln(stdout, strings.Repeat("═", width))
+	fmt.Fprintln(stdout)
+}
+
+// printPassageSection prints the centered reference followed by the wrapped
+// passage text to w.
+func printPassageSection(w io.Writer, reference, passage string, width int, opts DisplayOptions) {
+	passageText := cleanPassage(passage, opts)
 
 	// Center the reference
-	refPadding := (width - len(reference)) / 2
+	refPadding := (width - displayWidth(reference)) / 2
 	if refPadding < 0 {
 		refPadding = 0
 	}
-	fmt.Printf("%s%s\n", strings.Repeat(" ", refPadding), reference)
+	header := reference
+	if opts.ColorReference {
+		header = styled(referenceColor(reference), reference)
+	}
+	fmt.Fprintf(w, "%s%s\n", strings.Repeat(" ", refPadding), header)
+
+	fmt.Fprintln(w, strings.Repeat("─", width))
 
-	fmt.Println(strings.Repeat("─", width))
+	if opts.VerseLayout == verseLayoutTable {
+		printVerseTable(w, passage, width, opts)
+		return
+	}
 
 	// Word wrap and display the passage text
 	lines := strings.Split(passageText, "\n")
+	if opts.DropCap {
+		lines = printDropCap(w, lines, width-2)
+	}
 	for _, line := range lines {
-		wrappedLines := wrapText(line, width-2)
-		for _, wrapped := range wrappedLines {
-			fmt.Printf(" %s\n", wrapped)
+		parts := []string{line}
+		if opts.Selahs {
+			parts = selahPattern.Split(line, -1)
+		}
+		for i, part := range parts {
+			if i > 0 {
+				// Each Selah gets its own line, right-aligned
+				const selah = "Selah"
+				fmt.Fprintf(w, "%s%s\n", strings.Repeat(" ", width-1-len(selah)), styled(ansiItalic, selah))
+				part = strings.TrimLeft(part, " \t")
+				if part == "" {
+					continue
+				}
+			}
+			printWrapped(w, part, width, opts)
+		}
+	}
+}
+
+// printWrapped word wraps one line of passage text to fit in the box.
+func printWrapped(w io.Writer, line string, width int, opts DisplayOptions) {
+	var wrappedLines []string
+	if opts.Whitespace == whitespaceIndent {
+		wrappedLines = wrapIndented(line, width-2)
+	} else {
+		wrappedLines = wrapText(line, width-2)
+	}
+	for _, wrapped := range wrappedLines {
+		fmt.Fprintf(w, " %s\n", wrapped)
+	}
+}
+
+// printVerseTable prints the verses of passage as the rows of a table, each
+// verse number right-aligned in a gutter and the text wrapped beside it.
+func printVerseTable(w io.Writer, passage string, width int, opts DisplayOptions) {
+	// The numbers go in the gutter, and quotation marks would hide them
+	opts.HideVerseNumbers, opts.Quote = false, ""
+	type row struct {
+		number string
+		text   []string
+	}
+	var rows []row
+	gutter := 0
+	for _, line := range strings.Split(cleanPassage(passage, opts), "\n") {
+		line = strings.TrimSpace(line)
+		if m := verseNumberPattern.FindString(line); m != "" && strings.HasPrefix(line, m) {
+			number := strings.Trim(strings.TrimSpace(m), "[]")
+			gutter = max(gutter, displayWidth(number))
+			rows = append(rows, row{number: number})
+			line = line[len(m):]
+		} else if len(rows) == 0 {
+			rows = append(rows, row{}) // Text before the first verse number
+		}
+		if line != "" {
+			rows[len(rows)-1].text = append(rows[len(rows)-1].text, line)
+		}
+	}
+
+	textWidth := max(width-gutter-5, 10)
+	for _, r := range rows {
+		number := r.number
+		for _, line := range wrapText(strings.Join(strings.Fields(strings.Join(r.text, " ")), " "), textWidth) {
+			fmt.Fprintf(w, " %s%s │ %s\n", strings.Repeat(" ", gutter-displayWidth(number)), number, line)
+			number = ""
+		}
+	}
+}
+
+// displayPlain prints each passage as its reference, the separator of
+// opts.PlainSeparator and the unwrapped text, for piping into other tools.
+func displayPlain(verse *ESVResponse, opts DisplayOptions) {
+	for i, passage := range verse.Passages {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		var lines []string
+		for _, line := range strings.Split(cleanPassage(passage, opts), "\n") {
+			lines = append(lines, foldWhitespace(line, opts.Whitespace == whitespaceIndent))
+		}
+		header := passageHeader(verse, i, opts)
+		switch opts.PlainSeparator {
+		case plainSeparatorColon:
+			lines[0] = header + ": " + strings.TrimLeft(lines[0], " ")
+		case plainSeparatorDash:
+			lines[0] = header + " - " + strings.TrimLeft(lines[0], " ")
+		default:
+			lines = append([]string{header, ""}, lines...)
 		}
+		for _, line := range lines {
+			fmt.Fprintln(stdout, line)
+		}
+	}
+	if opts.WithDate && !verse.Fetched.IsZero() {
+		fmt.Fprintln(stdout)
+		fmt.Fprintln(stdout, "Fetched", verse.Fetched.Format(fetchedLayout))
 	}
+}
 
-	fmt.Println(strings.Repeat("═", width))
-	fmt.Println()
+// verseStartPattern matches a verse number marker along with the whitespace
+// before it.
+var verseStartPattern = regexp.MustCompile(`\s*(` + verseNumberPattern.String() + `)`)
+
+// cleanPassage lays out the verses of the passage text, trims the
+// surrounding whitespace and adds the quotation marks of opts.Quote.
+func cleanPassage(passage string, opts DisplayOptions) string {
+	if opts.VerseLayout == verseLayoutLines || opts.VerseLayout == verseLayoutTable {
+		passage = verseStartPattern.ReplaceAllString(passage, "\n$1")
+	}
+	if opts.HideVerseNumbers {
+		passage = stripVerseNumbers(passage)
+	}
+	if opts.Whitespace == whitespaceIndent {
+		// Only trim the end so the first line keeps its indentation
+		passage = strings.TrimRight(trimLeadingBlankLines(passage), " \t\r\n")
+	} else {
+		passage = strings.TrimSpace(passage)
+	}
+	if marks, ok := quoteMarks[opts.Quote]; ok && passage != "" {
+		indent := passage[:len(passage)-len(strings.TrimLeft(passage, " \t"))]
+		passage = indent + marks[0] + passage[len(indent):] + marks[1]
+	}
+	return passage
 }
 
+// announceFetch tells the user which reference is being fetched. Only the
+// box format has room for it; the others are meant for other programs.
+func announceFetch(reference string, opts DisplayOptions) {
+	if opts.Format == formatBox {
+		fmt.Fprintf(stdout, "Fetching: %s\n", reference)
+	}
+}
+
+// printJSON prints v as indented JSON, or on one line if compact, and
+// returns the exit code.
+func printJSON(v any, compact bool) int {
+	if err := writeJSON(v, compact); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeJSON writes v to stdout as indented JSON, or on one line if compact.
+func writeJSON(v any, compact bool) error {
+	var data []byte
+	var err error
+	if compact {
+		data, err = json.Marshal(v)
+	} else {
+		data, err = json.MarshalIndent(v, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("encoding JSON: %w", err)
+	}
+	if _, err := fmt.Fprintln(stdout, string(data)); err != nil {
+		return fmt.Errorf("writing JSON: %w", err)
+	}
+	return nil
+}
+
+// boxWidth returns the width of the verse box for the current terminal.
+func boxWidth() int {
+	termWidth := getTerminalWidth()
+	width := termWidth - 4 // Leave some margin
+	if width < 40 {
+		width = 40 // Minimum width
+	}
+	if width > 120 {
+		width = 120 // Cap max width for readability
+	}
+	return width
+}
+
+// wrapText word wraps text to lines of at most maxWidth columns.
 func wrapText(text string, maxWidth int) []string {
-	if len(text) <= maxWidth {
+	if displayWidth(text) <= maxWidth {
 		return []string{text}
 	}
+	return wrapTokens(splitTokens(text), func(int) int { return maxWidth })
+}
 
-	var result []string
-	words := strings.Fields(text)
-	currentLine := ""
+// wrapIndented wraps a line like wrapText, but collapses runs of whitespace
+// within the line and repeats its leading indentation on every wrapped line.
+func wrapIndented(line string, maxWidth int) []string {
+	indent, body := splitIndent(line)
+	body = strings.Join(strings.Fields(body), " ")
+	if body == "" {
+		return []string{""}
+	}
 
-	for _, word := range words {
-		if len(currentLine)+len(word)+1 > maxWidth {
-			if currentLine != "" {
-				result = append(result, currentLine)
-			}
-			currentLine = word
-		} else {
-			if currentLine == "" {
-				currentLine = word
-			} else {
-				currentLine += " " + word
-			}
+	// Deep indentation must still leave room for the text itself
+	if len(indent) > maxWidth/2 {
+		indent = indent[:maxWidth/2]
+	}
+
+	wrapped := wrapText(body, maxWidth-len(indent))
+	for i := range wrapped {
+		wrapped[i] = indent + wrapped[i]
+	}
+	return wrapped
+}
+
+// splitIndent splits a line into its leading indentation, with tabs
+// expanded, and the rest of the line.
+func splitIndent(line string) (indent, body string) {
+	body = strings.TrimLeft(line, " \t")
+	indent = strings.ReplaceAll(line[:len(line)-len(body)], "\t", "    ")
+	return indent, body
+}
+
+// foldWhitespace collapses runs of whitespace in line into single spaces,
+// optionally keeping its leading indentation.
+func foldWhitespace(line string, keepIndent bool) string {
+	indent, body := splitIndent(line)
+	body = strings.Join(strings.Fields(body), " ")
+	if !keepIndent || body == "" {
+		return body
+	}
+	return indent + body
+}
+
+func trimLeadingBlankLines(text string) string {
+	for {
+		line, rest, found := strings.Cut(text, "\n")
+		if !found || strings.TrimSpace(line) != "" {
+			return text
 		}
+		text = rest
 	}
+}
 
-	if currentLine != "" {
-		result = append(result, currentLine)
+// greeting returns the salutation for the time of day of now.
+func greeting(now time.Time) string {
+	switch hour := now.Hour(); {
+	case hour >= 5 && hour < 12:
+		return msg(msgGoodMorning)
+	case hour >= 12 && hour < 17:
+		return msg(msgGoodAfternoon)
+	default:
+		return msg(msgGoodEvening)
+	}
+}
+
+// printDailyHeader introduces the verse of the day, with the reading streak
+// unless streak is zero.
+func printDailyHeader(now time.Time, greet bool, streak int, opts DisplayOptions) {
+	var lines []string
+	if greet {
+		lines = append(lines, fmt.Sprintf("%s! %s, %s", greeting(now), msg(msgVerseOfTheDay), now.Format("Monday, January 2")))
+	}
+	if streak > 0 {
+		lines = append(lines, streakText(streak, opts))
+	}
+	if len(lines) == 0 {
+		return
 	}
 
-	return result
+	switch opts.Format {
+	case formatBox:
+		fmt.Fprintln(stdout)
+		for _, line := range lines {
+			fmt.Fprintf(stdout, " %s\n", line)
+		}
+	case formatPlain:
+		for _, line := range lines {
+			fmt.Fprintln(stdout, line)
+		}
+		fmt.Fprintln(stdout)
+	}
 }
 
 func main() {
+	defer flushOutput()
+	poetry := flag.Bool("poetry", false, "Keep the line breaks and indentation of poetic passages")
+	oldTestament := flag.Bool("ot", false, "Pick random verses and the verse of the day from the Old Testament")
+	newTestament := flag.Bool("nt", false, "Pick random verses and the verse of the day from the New Testament")
+	topic := flag.String("topic", "", "Pick random verses and the verse of the day about this topic (see the topics command)")
+	explainSelection := flag.Bool("explain-selection", false, "Explain on stderr how random and daily verses are chosen")
+	clock := flag.String("clock", clockLocal, "Date the daily verse goes by: local, utc or ntp (the local date by network time)")
+	date := flag.String("date", "", "Show the daily verse of this day, as YYYY-MM-DD, instead of today")
+	seedFromHostname := flag.Bool("seed-from-hostname", false, "Seed random/today selection from this machine's hostname and the date")
+	selahs := flag.Bool("selahs", false, "Include \"Selah\" notations, set apart on their own line")
+	verseNumbers := flag.Bool("verse-numbers", false, "Show verse numbers")
+	copyPassage := flag.Bool("copy", false, "Also copy the passage to the clipboard")
+	stripNumbers := flag.Bool("strip-numbers", false, "Leave verse numbers out of the text copied with -copy")
+	colorReference := flag.Bool("color-reference", false, "Color the reference, the same color for every verse of a chapter")
+	dropCap := flag.Bool("drop-cap", false, "Enlarge the first letter of the passage")
+	plain := flag.Bool("plain", false, "Print plain text without the box, same as -format plain")
+	plainSeparator := flag.String("plain-separator", plainSeparatorBlank, "Between the reference and the text in plain output: blank (line), colon or dash")
+	noStreak := flag.Bool("no-streak", false, "Omit the reading streak from the today command")
+	noCopyright := flag.Bool("no-copyright", false, "Never show the ESV copyright notice, e.g. with permission from the publisher")
+	noGreeting := flag.Bool("no-greeting", false, "Omit the greeting from the today command")
+	extraParams := paramFlag{}
+	flag.Var(extraParams, "param", "Extra API query parameter as key=value, overriding defaults (repeatable)")
+	fromClipboard := flag.Bool("from-clipboard", false, "Look up the reference found in the text on the clipboard")
+	fromFile := flag.String("from-file", "", "Read references to display, one per line, from a file (- for stdin)")
+	dedupe := flag.Bool("dedupe", false, "Skip references read with -from-file that repeat an earlier one")
+	printConfig := flag.Bool("print-config", false, "Print the settings in effect, from defaults, the config file, the environment and flags, and exit")
+	debug := flag.Bool("debug", false, "Print diagnostics to stderr")
+	sortOrder := flag.String("sort", sortInput, "Order of references read with -from-file: input or canonical")
+	translation := flag.String("translation", "", "Translation to fetch (default from the config file, otherwise ESV)")
+	rawResponse := flag.String("raw-response", "", "Write the API response bodies, exactly as received, to a file (- for stdout)")
+	retries := flag.Int("retries", defaultRetryPolicy.Retries, "Times to retry a request that was rate limited, timed out or hit a server error")
+	retryDNSDelay := flag.Duration("retry-dns-delay", defaultRetryPolicy.DNSDelay, "Delay before retrying a failed DNS lookup of the API, doubled for each retry (0 to only retry lookups that timed out)")
+	retryJitter := flag.String("retry-jitter", jitterFull, "Randomization of retry delays: none, full or equal")
+	concurrency := flag.Int("concurrency", 2, "Requests in flight at first with -from-file")
+	minConcurrency := flag.Int("min-concurrency", 1, "Fewest requests in flight when the API rate limits")
+	maxConcurrency := flag.Int("max-concurrency", 8, "Most requests in flight while requests succeed")
+	resetReferences := flag.Bool("reset-references", false, "Forget the canonical references remembered for earlier input")
+	noCache := flag.Bool("no-cache", false, "Don't read or write the response cache, same as -cache-backend none")
+	cacheBackend := flag.String("cache-backend", cacheMemory, "Where responses are cached: memory (for this run only), disk (across runs) or none")
+	cacheTTL := flag.Duration("cache-ttl", defaultCacheTTL, "How long cached responses are used without asking the API")
+	staleTimeout := flag.Duration("stale-timeout", 0, "Serve an expired cached response if the API takes longer than this, refreshing it in the background (0 disables)")
+	passages := flag.String("passages", passagesSeparate, "Layout for queries with several passages: separate or combined")
+	noBoxTop := flag.Bool("no-box-top", false, "Leave out the top border of the box")
+	noBoxBottom := flag.Bool("no-box-bottom", false, "Leave out the bottom border of the box")
+	withDate := flag.Bool("with-date", false, "Include when each verse was fetched in the output")
+	noTrailingNewline := flag.Bool("no-trailing-newline", false, "Don't end the output with newlines")
+	jsonOutput := flag.Bool("json", false, "Print JSON, same as -format json")
+	compactJSON := flag.Bool("compact-json", false, "Print JSON on a single line, one line per response")
+	showTranslation := flag.String("show-translation", showTranslationAuto, "Show the translation after the reference: auto (when several are in use), always or never")
+	citations := flag.String("citations", citationInline, "How -format markdown cites references: inline or footnote")
+	withHTML := flag.Bool("with-html", false, "Also fetch each passage as HTML, included in JSON output as \"html\"")
+	fields := flag.String("fields", "", "Comma-separated fields to include in JSON output, e.g. canonical,passages")
+	format := flag.String("format", formatBox, "Output format: "+strings.Join(outputFormats, ", "))
+	quote := flag.String("quote", "", "Put each passage in quotation marks: double, single, guillemets or straight")
+	verseLayout := flag.String("verse-layout", verseLayoutProse, "Layout of the verses in a passage: prose, lines (one verse per line) or table (verse numbers in a gutter)")
+	flag.StringVar(&ambiguousWidth, "ambiguous-width", ambiguousNarrow, "Columns taken by East Asian ambiguous-width characters: narrow (1) or wide (2)")
+	whitespace := flag.String("whitespace", "", "Whitespace handling: fold or indent (default indent with -poetry, otherwise fold)")
+	oncePerDay := flag.Bool("once-per-day", false, "Only show output the first time each day, e.g. from a shell startup file")
+	force := flag.Bool("force", false, "Show output even if -once-per-day already did today")
+	outputBufferFlag := flag.String("output-buffer", outputBufferPassage, "Output buffering: passage (flush after each passage), full (flush on exit) or none")
+	flag.Usage = usage
+	args, err := parseArgs(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		exit(2)
+	}
+
+	if *debug {
+		debugLog.SetOutput(os.Stderr)
+	}
+	if *explainSelection {
+		selectionLog.SetOutput(os.Stderr)
+	}
+	if !slices.Contains(outputBufferModes, *outputBufferFlag) {
+		fmt.Fprintf(os.Stderr, "Error: invalid -output-buffer %q (want %s)\n", *outputBufferFlag, strings.Join(outputBufferModes, ", "))
+		exit(2)
+	}
+	stdout = bufferOutput(os.Stdout, *outputBufferFlag)
+	if *noTrailingNewline {
+		stdout = &newlineTrimmer{w: stdout}
+	}
+
+	if *whitespace == "" {
+		*whitespace = whitespaceFold
+		if *poetry {
+			*whitespace = whitespaceIndent
+		}
+	}
+	if *whitespace != whitespaceFold && *whitespace != whitespaceIndent {
+		fmt.Fprintf(os.Stderr, "Error: invalid -whitespace %q (want fold or indent)\n", *whitespace)
+		exit(2)
+	}
+	if *passages != passagesSeparate && *passages != passagesCombined {
+		fmt.Fprintf(os.Stderr, "Error: invalid -passages %q (want separate or combined)\n", *passages)
+		exit(2)
+	}
+	if ambiguousWidth != ambiguousNarrow && ambiguousWidth != ambiguousWide {
+		fmt.Fprintf(os.Stderr, "Error: invalid -ambiguous-width %q (want narrow or wide)\n", ambiguousWidth)
+		exit(2)
+	}
+	if _, ok := quoteMarks[*quote]; *quote != "" && !ok {
+		fmt.Fprintf(os.Stderr, "Error: invalid -quote %q (want double, single, guillemets or straight)\n", *quote)
+		exit(2)
+	}
+	if !slices.Contains([]string{verseLayoutProse, verseLayoutLines, verseLayoutTable}, *verseLayout) {
+		fmt.Fprintf(os.Stderr, "Error: invalid -verse-layout %q (want prose, lines or table)\n", *verseLayout)
+		exit(2)
+	}
+	if !slices.Contains([]string{showTranslationAuto, showTranslationAlways, showTranslationNever}, *showTranslation) {
+		fmt.Fprintf(os.Stderr, "Error: invalid -show-translation %q (want auto, always or never)\n", *showTranslation)
+		exit(2)
+	}
+	if !slices.Contains([]string{cacheDisk, cacheMemory, cacheNone}, *cacheBackend) {
+		fmt.Fprintf(os.Stderr, "Error: invalid -cache-backend %q (want disk, memory or none)\n", *cacheBackend)
+		exit(2)
+	}
+	if *noCache {
+		*cacheBackend = cacheNone
+	}
+	if !slices.Contains([]string{jitterNone, jitterFull, jitterEqual}, *retryJitter) {
+		fmt.Fprintf(os.Stderr, "Error: invalid -retry-jitter %q (want none, full or equal)\n", *retryJitter)
+		exit(2)
+	}
+	if *citations != citationInline && *citations != citationFootnote {
+		fmt.Fprintf(os.Stderr, "Error: invalid -citations %q (want inline or footnote)\n", *citations)
+		exit(2)
+	}
+	if !slices.Contains([]string{plainSeparatorBlank, plainSeparatorColon, plainSeparatorDash}, *plainSeparator) {
+		fmt.Fprintf(os.Stderr, "Error: invalid -plain-separator %q (want blank, colon or dash)\n", *plainSeparator)
+		exit(2)
+	}
+	if *sortOrder != sortInput && *sortOrder != sortCanonical {
+		fmt.Fprintf(os.Stderr, "Error: invalid -sort %q (want input or canonical)\n", *sortOrder)
+		exit(2)
+	}
+	if *plain && *jsonOutput {
+		fmt.Fprintln(os.Stderr, "Error: -plain and -json can't be combined")
+		exit(2)
+	}
+	if *plain {
+		*format = formatPlain
+	}
+	if *jsonOutput || *compactJSON {
+		*format = formatJSON
+	}
+	if !slices.Contains(outputFormats, *format) {
+		fmt.Fprintf(os.Stderr, "Error: invalid -format %q (want %s)\n", *format, strings.Join(outputFormats, ", "))
+		exit(2)
+	}
+	if *withHTML && *format != formatJSON {
+		fmt.Fprintln(os.Stderr, "Error: -with-html only applies to JSON output")
+		exit(2)
+	}
+	if *verseLayout == verseLayoutTable && *format != formatBox {
+		fmt.Fprintf(os.Stderr, "Error: -verse-layout table only applies to the box format (use lines for %s)\n", *format)
+		exit(2)
+	}
+	var jsonFields []string
+	if *fields != "" {
+		if *format != formatJSON {
+			fmt.Fprintln(os.Stderr, "Error: -fields only applies to JSON output")
+			exit(2)
+		}
+		var err error
+		if jsonFields, err = parseFields(*fields); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid -fields: %v\n", err)
+			exit(2)
+		}
+	}
+	displayOpts := DisplayOptions{Whitespace: *whitespace, Passages: *passages, Format: *format, DropCap: *dropCap, Selahs: *selahs,
+		Copy: *copyPassage, StripNumbers: *stripNumbers, ColorReference: *colorReference,
+		CompactJSON: *compactJSON, Fields: jsonFields, VerseLayout: *verseLayout, Quote: *quote, WithDate: *withDate,
+		NoBoxTop: *noBoxTop, NoBoxBottom: *noBoxBottom, PlainSeparator: *plainSeparator}
+
+	if *fromClipboard {
+		if len(args) > 0 || *fromFile != "" {
+			fmt.Fprintln(os.Stderr, "Error: -from-clipboard can't be combined with a reference or -from-file")
+			exit(2)
+		}
+		text, err := readClipboard()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: reading the clipboard: %v\n", err)
+			exit(1)
+		}
+		reference, ok := findReference(text)
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Error: no reference found on the clipboard")
+			exit(1)
+		}
+		debugLog.Printf("found %q on the clipboard", reference)
+		args = []string{reference}
+	}
+
+	var subcommand string
+	if len(args) > 0 {
+		subcommand = args[0]
+	}
+	switch subcommand {
+	case "today", "random", "checksum", "streak", "topics", "ping", "present":
+		if len(args) > 1 {
+			fmt.Fprintf(os.Stderr, "Error: %s takes no arguments, got %q\n", subcommand, strings.Join(args[1:], " "))
+			fmt.Fprintf(os.Stderr, "Run '%s -help' for usage.\n", flag.CommandLine.Name())
+			exit(2)
+		}
+	case "search":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "Error: search needs words to look for, e.g. search living water")
+			exit(2)
+		}
+	}
+
+	if *printConfig {
+		exit(runPrintConfig(displayOpts, *translation))
+	}
+
+	// Commands that don't need the API
+	switch subcommand {
+	case "books":
+		exit(runBooks(args[1:], displayOpts))
+	case "checksum":
+		exit(runChecksum())
+	case "streak":
+		exit(runStreak(displayOpts))
+	case "topics":
+		exit(runTopics(displayOpts))
+	case "bookmarks":
+		exit(runBookmarks(args[1:], displayOpts))
+	case "pack":
+		exit(runPack(args[1:], displayOpts))
+	}
+	if *format == formatCSV || *format == formatTSV {
+		displayOpts.Table = newTableWriter(*format, *withDate)
+	}
+	if *format == formatMarkdown {
+		displayOpts.Markdown = &markdownDocument{Citations: *citations}
+	}
+
+	if !slices.Contains(clockSources, *clock) {
+		fmt.Fprintf(os.Stderr, "Error: invalid -clock %q (want %s)\n", *clock, strings.Join(clockSources, ", "))
+		exit(2)
+	}
+	if *date != "" && subcommand != "today" {
+		fmt.Fprintln(os.Stderr, "Error: -date only applies to today")
+		exit(2)
+	}
+
+	// -once-per-day runs on every shell start, so once today's verse was
+	// shown by the local clock, don't ask a time server
+	if *oncePerDay && !*force && *clock == clockNTP {
+		if local, err := verseDay(clockLocal, *date); err == nil && shownToday(local) {
+			debugLog.Printf("already shown today by the local clock, see -force")
+			exit(0)
+		}
+	}
+
+	// The day of the verse for today, and of the output for -once-per-day
+	var day time.Time
+	if subcommand == "today" || *oncePerDay {
+		if day, err = verseDay(*clock, *date); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(2)
+		}
+	}
+	if *oncePerDay && !*force && shownToday(day) {
+		debugLog.Printf("already shown today, see -force")
+		exit(0)
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+
+	// Only the ESV needs a token; public domain translations come from
+	// bible-api.com
 	apiKey := os.Getenv("ESV_TOKEN")
-	if apiKey == "" {
-		fmt.Println("Please set the ESV_TOKEN environment variable with your ESV API key.")
-		fmt.Println("You can get a free API key at: https://api.esv.org/")
-		fmt.Println("\nExample: export ESV_TOKEN='your_api_key_here'")
-		os.Exit(1)
+	defaultESV := cmp.Or(strings.ToUpper(*translation), strings.ToUpper(config.Translation), defaultTranslation) == "ESV"
+	if apiKey == "" && (defaultESV || subcommand == "ping") {
+		fmt.Fprintln(stdout, "Please set the ESV_TOKEN environment variable with your ESV API key.")
+		fmt.Fprintln(stdout, "You can get a free API key at: https://api.esv.org/")
+		fmt.Fprintln(stdout, "\nExample: export ESV_TOKEN='your_api_key_here'")
+		exit(1)
+	}
+
+	if subcommand == "ping" {
+		exit(runPing(apiKey))
+	}
+
+	if *minConcurrency < 1 || *maxConcurrency < *minConcurrency ||
+		*concurrency < *minConcurrency || *concurrency > *maxConcurrency {
+		fmt.Fprintln(os.Stderr, "Error: want 1 <= -min-concurrency <= -concurrency <= -max-concurrency")
+		exit(2)
+	}
+
+	retry := defaultRetryPolicy
+	retry.Retries = *retries
+	retry.Jitter = *retryJitter
+	retry.DNSDelay = max(*retryDNSDelay, 0)
+	fetchOpts := FetchOptions{
+		PoetryLines:  *poetry,
+		Selahs:       *selahs,
+		VerseNumbers: *verseNumbers || *verseLayout != verseLayoutProse,
+		ExtraParams:  url.Values(extraParams),
+		HTML:         *withHTML,
+		Retry:        retry,
+		Limiter:      newAdaptiveLimiter(*concurrency, *minConcurrency, *maxConcurrency),
+
+		MaxSpanVerses:   config.maxSpanVerses(),
+		CopyrightVerses: config.copyrightVerses(),
+	}
+	if *noCopyright {
+		fetchOpts.CopyrightVerses = -1
+	}
+	fetchOpts.Cache, err = newResponseCache(*cacheBackend, *cacheTTL)
+	if err != nil {
+		debugLog.Printf("disabling cache: %v", err)
+		fetchOpts.Cache = nopCache{}
+	}
+	if *cacheBackend != cacheNone {
+		fetchOpts.StaleTimeout = *staleTimeout
+	}
+	switch *rawResponse {
+	case "":
+	case "-":
+		rawOutput = &pendingOutput{}
+		fetchOpts.RawResponse = rawOutput
+	default:
+		f, err := os.Create(*rawResponse)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		defer f.Close()
+		fetchOpts.RawResponse = f
+	}
+
+	// The lines layout needs the verse numbers to find the verses
+	displayOpts.HideVerseNumbers = fetchOpts.VerseNumbers && !*verseNumbers
+
+	providers := make(map[string]Provider)
+	if apiKey != "" {
+		providers["ESV"] = NewBibleClient(apiKey, fetchOpts)
+	}
+	publicDomain := NewBibleClient("", fetchOpts)
+	for _, name := range bibleAPITranslations {
+		providers[name] = newBibleAPIProvider(publicDomain, name)
+	}
+	resolver := newReferenceResolver(fetchOpts.Cache)
+	if *resetReferences {
+		resolver.Reset()
 	}
+	client := NewDispatcher(providers, config, *translation, resolver)
+	displayOpts.ShowTranslation = *showTranslation == showTranslationAlways ||
+		*showTranslation == showTranslationAuto && client.mixedTranslations()
 
-	client := NewBibleClient(apiKey)
+	if subcommand == "search" {
+		exit(runSearch(client, strings.Join(args[1:], " "), displayOpts))
+	}
 
-	if len(os.Args) > 1 {
-		reference := strings.Join(os.Args[1:], " ")
-		fmt.Printf("Fetching: %s\n", reference)
-		verse, err := client.FetchVerse(reference)
+	if *fromFile != "" {
+		if len(args) > 0 && subcommand != "present" {
+			fmt.Fprintln(os.Stderr, "Error: -from-file can't be combined with a reference")
+			exit(2)
+		}
+		references, err := readReferenceFile(*fromFile)
 		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: reading references: %v\n", err)
+			exit(1)
+		}
+		if *dedupe {
+			var dropped int
+			references, dropped = dedupeReferences(references)
+			debugLog.Printf("collapsed %d duplicate references", dropped)
+		}
+		if *sortOrder == sortCanonical {
+			sortReferences(references)
+		}
+		if subcommand == "present" {
+			exit(runPresent(client, references, nil, displayOpts))
+		}
+		shown, ok := runBatch(client, references, fetchOpts.Limiter.Max(), displayOpts)
+		if displayOpts.Markdown != nil {
+			displayOpts.Markdown.finish()
+		}
+		recordOutput(shown > 0, ok && *oncePerDay, day)
+		client.WaitForRefreshes()
+		if !ok {
+			exit(1)
+		}
+		return
+	}
+
+	var testament string
+	switch {
+	case *oldTestament && *newTestament:
+		fmt.Fprintln(os.Stderr, "Error: -ot and -nt can't be combined")
+		exit(2)
+	case *oldTestament:
+		testament = testamentOld
+	case *newTestament:
+		testament = testamentNew
+	}
+	var pool []string
+	if subcommand == "" || subcommand == "random" || subcommand == "today" || subcommand == "present" {
+		if pool, err = versePool(testament, *topic, config); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			if errors.Is(err, errNoBuiltInVerses) {
+				exit(1)
+			}
+			exit(2)
 		}
-		displayVerse(verse)
-	} else {
-		verse, err := client.GetRandomVerse()
+		filters := poolDescription(testament, *topic, config)
+		if filters == "" {
+			filters = "no filters"
+		}
+		selectionLog.Printf("pool of %d of the %d built-in verses, from %s", len(pool), len(bibleVerses), filters)
+	}
+
+	if subcommand == "present" {
+		exit(runPresent(client, nil, pool, displayOpts))
+	}
+
+	var machineID string
+	if *seedFromHostname {
+		hostname, err := os.Hostname()
 		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: reading hostname: %v\n", err)
+			exit(1)
+		}
+		machineID = hostname
+	}
+
+	var verse *ESVResponse
+	command := strings.Join(args, " ")
+	switch {
+	case command == "today":
+		explainSeed(day, *clock, *date, machineID)
+		verse, err = client.GetSeededVerse(dailySeed(day, machineID), pool)
+		if err == nil {
+			streak := streakAfterReading(time.Now())
+			if *noStreak {
+				streak = 0
+			}
+			printDailyHeader(day, !*noGreeting, streak, displayOpts)
+		}
+	case command == "random" || command == "":
+		if *seedFromHostname {
+			if day.IsZero() {
+				day, _ = verseDay(*clock, "")
+			}
+			explainSeed(day, *clock, "", machineID)
+			verse, err = client.GetSeededVerse(dailySeed(day, machineID), pool)
+		} else {
+			verse, err = client.GetRandomVerse(pool)
+		}
+	default:
+		if err := validateReference(command, fetchOpts.MaxSpanVerses); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
-		displayVerse(verse)
+		announceFetch(command, displayOpts)
+		verse, err = client.FetchVerse(command)
+		if err == nil && len(verse.Passages) == 0 {
+			if suggestions := suggestReferences(verse, command); len(suggestions) > 0 {
+				chosen := chooseSuggestion(command, suggestions, displayOpts)
+				if chosen == "" {
+					exit(1)
+				}
+				announceFetch(chosen, displayOpts)
+				verse, err = client.FetchVerse(chosen)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	if err := displayVerse(verse, displayOpts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	if displayOpts.Markdown != nil {
+		displayOpts.Markdown.finish()
 	}
+	shown := len(verse.Passages) > 0
+	recordOutput(shown, shown && *oncePerDay, day)
+	client.WaitForRefreshes()
 }
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// captureOutput runs f with stdout redirected to a buffer and returns what
+// it wrote.
+func captureOutput(t *testing.T, f func()) string {
+	t.Helper()
+	var buf bytes.Buffer
+	saved := stdout
+	stdout = &buf
+	t.Cleanup(func() { stdout = saved })
+	f()
+	return buf.String()
+}
+
+// parseResponse decodes an API response for a test.
+func parseResponse(t *testing.T, body string) *ESVResponse {
+	t.Helper()
+	var verse ESVResponse
+	if err := json.Unmarshal([]byte(body), &verse); err != nil {
+		t.Fatalf("parsing response: %v", err)
+	}
+	return &verse
+}
+
+const twoPassageResponse = `{
+	"query": "John 3:16; Romans 8:28",
+	"canonical": "John 3:16; Romans 8:28",
+	"passages": ["For God so loved the world", "And we know that for those who love God"],
+	"passage_meta": [{"canonical": "John 3:16"}, {"canonical": "Romans 8:28"}]
+}`
+
+func TestDisplayVerseMultiplePassages(t *testing.T) {
+	tests := []struct {
+		name string
+		opts DisplayOptions
+		want []string // In this order
+	}{
+		{
+			name: "plain",
+			opts: DisplayOptions{Format: formatPlain},
+			want: []string{"John 3:16\n", "For God so loved the world", "Romans 8:28\n", "And we know"},
+		},
+		{
+			name: "separate boxes",
+			opts: DisplayOptions{Format: formatBox, Passages: passagesSeparate},
+			want: []string{"John 3:16\n", "For God so loved the world", "═", "Romans 8:28\n", "And we know"},
+		},
+		{
+			name: "combined box",
+			opts: DisplayOptions{Format: formatBox, Passages: passagesCombined},
+			want: []string{"John 3:16\n", "For God so loved the world", "┄", "Romans 8:28\n", "And we know"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verse := parseResponse(t, twoPassageResponse)
+			got := captureOutput(t, func() { displayVerse(verse, tt.opts) })
+			rest := got
+			for _, want := range tt.want {
+				i := strings.Index(rest, want)
+				if i < 0 {
+					t.Fatalf("output lacks %q after the earlier parts:\n%s", want, got)
+				}
+				rest = rest[i+len(want):]
+			}
+		})
+	}
+}
+
+func TestPassageReference(t *testing.T) {
+	verse := parseResponse(t, twoPassageResponse)
+	for i, want := range []string{"John 3:16", "Romans 8:28", "John 3:16; Romans 8:28"} {
+		if got := passageReference(verse, i); got != want {
+			t.Errorf("passageReference(verse, %d) = %q, want %q", i, got, want)
+		}
+	}
+}
+
+// newTestClient returns a client of the API served by handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc, options FetchOptions) *BibleClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client := NewBibleClient("test-token", options)
+	client.baseURL = server.URL + "/text/"
+	client.htmlURL = server.URL + "/html/"
+	client.searchURL = server.URL + "/search/"
+	return client
+}
+
+func TestValidateReferenceLength(t *testing.T) {
+	tests := []struct {
+		name      string
+		reference string
+		wantErr   bool
+	}{
+		{"short", "John 3:16", false},
+		{"at the limit", strings.Repeat("a", maxReferenceLength), false},
+		{"over the limit", strings.Repeat("a", maxReferenceLength+1), true},
+		{"megabytes", strings.Repeat("John 3:16; ", 100000), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateReference(tt.reference, 0)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateReference(%d bytes) = %v, want error %v", len(tt.reference), err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFetchVerseRejectsLongReference(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request for %d bytes", len(r.URL.RawQuery))
+	}, FetchOptions{})
+	if _, err := client.FetchVerse(strings.Repeat("a", maxReferenceLength+1)); err == nil {
+		t.Error("FetchVerse of an over-long reference succeeded, want an error")
+	}
+}
+
+func TestDisplayVerseEmptyCanonical(t *testing.T) {
+	verse := parseResponse(t, `{"query": "jn 3:16", "canonical": "", "passages": ["For God so loved the world"]}`)
+	for _, format := range []string{formatPlain, formatBox} {
+		t.Run(format, func(t *testing.T) {
+			got := captureOutput(t, func() {
+				displayVerse(verse, DisplayOptions{Format: format, Passages: passagesSeparate})
+			})
+			if !strings.Contains(got, "jn 3:16\n") {
+				t.Errorf("header lacks the query as typed:\n%s", got)
+			}
+			for _, line := range strings.Split(got, "\n") {
+				if line != "" && strings.TrimSpace(line) == "" {
+					t.Errorf("output has a header of only spaces:\n%s", got)
+				}
+			}
+		})
+	}
+}
+
+// truncatedBody answers like the API but breaks the connection off after
+// half of body.
+func truncatedBody(t *testing.T, w http.ResponseWriter, body string) {
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		t.Fatalf("hijacking the connection: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n%s", len(body), body[:len(body)/2])
+	buf.Flush()
+}
+
+func TestFetchVerseIncompleteBody(t *testing.T) {
+	const body = `{"canonical": "John 3:16", "passages": ["For God so loved the world"]}`
+	tests := []struct {
+		name      string
+		truncated int // Requests cut off before one succeeds
+		retries   int
+		wantErr   error
+	}{
+		{"retried", 1, 1, nil},
+		{"out of retries", 2, 1, errIncompleteResponse},
+		{"no retries", 1, 0, errIncompleteResponse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= tt.truncated {
+					truncatedBody(t, w, body)
+					return
+				}
+				fmt.Fprint(w, body)
+			}, FetchOptions{Retry: retryPolicy{Retries: tt.retries}})
+
+			verse, err := client.FetchVerse("John 3:16")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("FetchVerse error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchVerse: %v", err)
+			}
+			if verse.Canonical != "John 3:16" {
+				t.Errorf("Canonical = %q, want John 3:16", verse.Canonical)
+			}
+		})
+	}
+}
+
+func TestFetchVerseInvalidUTF8(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{\"canonical\": \"John 3:16\", \"passages\": [\"For God so lo\xffved the w\xc3orld\"]}"))
+	}, FetchOptions{})
+	verse, err := client.FetchVerse("John 3:16")
+	if err != nil {
+		t.Fatalf("FetchVerse: %v", err)
+	}
+	const want = "For God so lo�ved the w�orld"
+	if len(verse.Passages) != 1 || verse.Passages[0] != want {
+		t.Errorf("Passages = %q, want [%q]", verse.Passages, want)
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestDisplayVerseJSONWriteError(t *testing.T) {
+	saved := stdout
+	stdout = failingWriter{}
+	t.Cleanup(func() { stdout = saved })
+
+	verse := parseResponse(t, twoPassageResponse)
+	for _, opts := range []DisplayOptions{
+		{Format: formatJSON},
+		{Format: formatJSON, Fields: []string{"canonical"}},
+	} {
+		if err := displayVerse(verse, opts); err == nil {
+			t.Errorf("displayVerse with fields %q succeeded on a failing writer, want an error", opts.Fields)
+		}
+	}
+}
+
+func TestParseVerses(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		wantVerses int
+		wantErr    bool
+	}{
+		{"empty", "", 0, false},
+		{"whitespace", " \n", 0, false},
+		{"empty list", `{"verses": []}`, 0, false},
+		{"verses", `{"verses": ["John 3:16", "Romans 8:28"], "topics": {"love": ["John 3:16"]}}`, 2, false},
+		{"unknown topic verse", `{"verses": ["John 3:16"], "topics": {"love": ["1 John 4:19"]}}`, 0, true},
+		{"invalid", `{"verses": `, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := parseVerses([]byte(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseVerses error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(data.Verses) != tt.wantVerses {
+				t.Errorf("parseVerses gave %d verses, want %d", len(data.Verses), tt.wantVerses)
+			}
+		})
+	}
+}
+
+func TestFetchVersePoetryLinesParam(t *testing.T) {
+	tests := []struct {
+		name    string
+		options FetchOptions
+		want    string
+	}{
+		{"default off", FetchOptions{}, "false"},
+		{"flag on", FetchOptions{PoetryLines: true}, "true"},
+		{"-param replaces the default", FetchOptions{PoetryLines: true, ExtraParams: url.Values{"include-poetry-lines": {"false"}}}, "false"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if got := r.URL.Query()["include-poetry-lines"]; !slices.Equal(got, []string{tt.want}) {
+					t.Errorf("include-poetry-lines = %q, want [%q]", got, tt.want)
+				}
+				fmt.Fprint(w, `{"canonical": "Psalm 23:1", "passages": ["The LORD is my shepherd"]}`)
+			}, tt.options)
+			if _, err := client.FetchVerse("Psalm 23:1"); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestPrintVerseTable(t *testing.T) {
+	const passage = "  [8] Blessed is the man\n    who walks not in the counsel of the wicked, [9] nor stands in the way of sinners; [10] but his delight is in the law."
+	want := "" +
+		"  8 │ Blessed is the man who walks not\n" +
+		"    │ in the counsel of the wicked,\n" +
+		"  9 │ nor stands in the way of sinners;\n" +
+		" 10 │ but his delight is in the law.\n"
+	var out bytes.Buffer
+	printVerseTable(&out, passage, 40, DisplayOptions{VerseLayout: verseLayoutTable, HideVerseNumbers: true, Quote: "double"})
+	if got := out.String(); got != want {
+		t.Errorf("printVerseTable() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestValidateReferenceSpan(t *testing.T) {
+	tests := []struct {
+		reference string
+		maxSpan   int
+		wantErr   bool
+	}{
+		{"Isaiah", defaultMaxSpanVerses, false},
+		{"Psalms", defaultMaxSpanVerses, false},
+		{"Psalm 1-150", defaultMaxSpanVerses, false},
+		{"Psalms", 2460, true},
+		{"Genesis-Exodus", defaultMaxSpanVerses, true},
+		{"Genesis-Revelation", defaultMaxSpanVerses, true},
+		{"Genesis-Revelation", 0, false},
+		{"Psalm 119", 100, true},
+		{"John 3:16-18", 3, false},
+	}
+	for _, tt := range tests {
+		if err := validateReference(tt.reference, tt.maxSpan); (err != nil) != tt.wantErr {
+			t.Errorf("validateReference(%q, %d) = %v, want error %v", tt.reference, tt.maxSpan, err, tt.wantErr)
+		}
+	}
+}
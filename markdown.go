@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Citation styles of the markdown format.
+const (
+	citationInline   = "inline"   // The reference follows each quote
+	citationFootnote = "footnote" // A numbered footnote at the end of the document
+)
+
+// markdownDocument collects the state of markdown output that spans all the
+// verses of a run, such as the footnotes of -citations footnote.
+type markdownDocument struct {
+	Citations string
+	quotes    int
+	footnotes []string
+}
+
+// displayMarkdown writes each passage as a block quote, cited inline or with
+// a footnote marker whose footnote is written by finish.
+func displayMarkdown(verse *ESVResponse, opts DisplayOptions) {
+	doc := opts.Markdown
+	for i, passage := range verse.Passages {
+		if doc.quotes > 0 {
+			fmt.Fprintln(stdout)
+		}
+		doc.quotes++
+
+		// Indentation would turn the quote into a code block
+		var lines []string
+		for _, line := range strings.Split(cleanPassage(passage, opts), "\n") {
+			lines = append(lines, foldWhitespace(line, false))
+		}
+		reference := passageHeader(verse, i, opts)
+		if doc.Citations == citationFootnote {
+			doc.footnotes = append(doc.footnotes, reference)
+			lines[len(lines)-1] += fmt.Sprintf("[^%d]", len(doc.footnotes))
+		}
+		for j, line := range lines {
+			if j < len(lines)-1 {
+				line += "  " // Keeps poetry's line breaks
+			}
+			fmt.Fprintf(stdout, "> %s\n", line)
+		}
+		if doc.Citations == citationInline {
+			fmt.Fprintf(stdout, ">\n> — %s\n", reference)
+		}
+	}
+}
+
+// finish writes the footnotes collected from all the passages, if any.
+func (doc *markdownDocument) finish() {
+	if len(doc.footnotes) == 0 {
+		return
+	}
+	fmt.Fprintln(stdout)
+	for i, reference := range doc.footnotes {
+		fmt.Fprintf(stdout, "[^%d]: %s\n", i+1, reference)
+	}
+}
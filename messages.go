@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// Message keys for user-facing text that varies with the user's language.
+const (
+	msgGoodMorning   = "good-morning"
+	msgGoodAfternoon = "good-afternoon"
+	msgGoodEvening   = "good-evening"
+	msgVerseOfTheDay = "verse-of-the-day"
+	msgDayStreak     = "day-streak" // Takes the number of days
+)
+
+// messages maps a language code to its translations of each message key.
+// English is the fallback for missing languages and keys.
+var messages = map[string]map[string]string{
+	"en": {
+		msgGoodMorning:   "Good morning",
+		msgGoodAfternoon: "Good afternoon",
+		msgGoodEvening:   "Good evening",
+		msgVerseOfTheDay: "Verse of the day",
+		msgDayStreak:     "%d day streak",
+	},
+	"es": {
+		msgGoodMorning:   "Buenos días",
+		msgGoodAfternoon: "Buenas tardes",
+		msgGoodEvening:   "Buenas noches",
+		msgVerseOfTheDay: "Versículo del día",
+		msgDayStreak:     "racha de %d días",
+	},
+	"de": {
+		msgGoodMorning:   "Guten Morgen",
+		msgGoodAfternoon: "Guten Tag",
+		msgGoodEvening:   "Guten Abend",
+		msgVerseOfTheDay: "Vers des Tages",
+		msgDayStreak:     "%d Tage in Folge",
+	},
+}
+
+// userLanguage returns the two-letter language code from the locale
+// environment, e.g. "de" for LANG=de_DE.UTF-8.
+func userLanguage() string {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(env); value != "" && value != "C" && value != "POSIX" {
+			return strings.ToLower(value[:min(2, len(value))])
+		}
+	}
+	return "en"
+}
+
+// msg returns the text for key in the user's language.
+func msg(key string) string {
+	if text, ok := messages[userLanguage()][key]; ok {
+		return text
+	}
+	return messages["en"][key]
+}
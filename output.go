@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// stdout receives all regular output, so that it can be post-processed in
+// one place.
+var stdout io.Writer = os.Stdout
+
+// Buffering modes of the output, from -output-buffer.
+const (
+	outputBufferPassage = "passage" // Flushed after each passage, so output streams in
+	outputBufferFull    = "full"    // Flushed on exit, the fastest for large outputs
+	outputBufferNone    = "none"    // Every write goes straight out
+)
+
+var outputBufferModes = []string{outputBufferPassage, outputBufferFull, outputBufferNone}
+
+var (
+	outputBuffer     *bufio.Writer // Under stdout, unless unbuffered
+	outputBufferMode = outputBufferNone
+)
+
+// bufferOutput returns w buffered according to mode, for stdout.
+func bufferOutput(w io.Writer, mode string) io.Writer {
+	outputBufferMode = mode
+	if mode == outputBufferNone {
+		outputBuffer = nil
+		return w
+	}
+	outputBuffer = bufio.NewWriter(w)
+	return outputBuffer
+}
+
+// pendingOutput holds output written from other goroutines, which only the
+// main goroutine may pass on to stdout.
+type pendingOutput struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (p *pendingOutput) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.buf.Write(b)
+}
+
+// writeTo passes what was written so far on to w.
+func (p *pendingOutput) writeTo(w io.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.buf.WriteTo(w); err != nil {
+		debugLog.Printf("writing output: %v", err)
+	}
+}
+
+// rawOutput, if set, collects the response bodies of -raw-response - from
+// the fetches, which may run concurrently, until they are written out ahead
+// of the next passage.
+var rawOutput *pendingOutput
+
+// writePendingOutput writes out the output other goroutines left in
+// rawOutput. Only the main goroutine calls it.
+func writePendingOutput() {
+	if rawOutput != nil {
+		rawOutput.writeTo(stdout)
+	}
+}
+
+// flushOutput writes out everything buffered so far.
+func flushOutput() {
+	writePendingOutput()
+	if outputBuffer == nil {
+		return
+	}
+	if err := outputBuffer.Flush(); err != nil {
+		debugLog.Printf("writing output: %v", err)
+	}
+}
+
+// passageDone marks the end of a passage's output, which is flushed unless
+// the whole output is buffered.
+func passageDone() {
+	if outputBufferMode != outputBufferFull {
+		flushOutput()
+	}
+}
+
+// exit flushes the output and exits with code.
+func exit(code int) {
+	flushOutput()
+	os.Exit(code)
+}
+
+// newlineTrimmer passes writes through to w, except that it holds back
+// trailing newlines until more output follows. Output therefore never ends
+// with a newline; the held back newlines are dropped.
+type newlineTrimmer struct {
+	w       io.Writer
+	pending int // Newlines held back
+}
+
+func (t *newlineTrimmer) Write(p []byte) (int, error) {
+	content := bytes.TrimRight(p, "\n")
+	if len(content) == 0 {
+		t.pending += len(p)
+		return len(p), nil
+	}
+
+	if t.pending > 0 {
+		if _, err := t.w.Write(bytes.Repeat([]byte("\n"), t.pending)); err != nil {
+			return 0, err
+		}
+		t.pending = 0
+	}
+	if _, err := t.w.Write(content); err != nil {
+		return 0, err
+	}
+	t.pending = len(p) - len(content)
+	return len(p), nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// setOutput makes stdout write to a buffer, as main does for os.Stdout,
+// restoring the output globals when the test ends.
+func setOutput(t *testing.T, mode string) *bytes.Buffer {
+	t.Helper()
+	savedStdout, savedBuffer, savedMode, savedRaw := stdout, outputBuffer, outputBufferMode, rawOutput
+	t.Cleanup(func() {
+		stdout, outputBuffer, outputBufferMode, rawOutput = savedStdout, savedBuffer, savedMode, savedRaw
+	})
+	var out bytes.Buffer
+	stdout = bufferOutput(&out, mode)
+	rawOutput = nil
+	return &out
+}
+
+func TestBufferOutput(t *testing.T) {
+	tests := []struct {
+		mode          string
+		afterWrite    string
+		afterPassage  string
+		afterFlushing string
+	}{
+		{outputBufferNone, "John 3:16\n", "John 3:16\n", "John 3:16\n"},
+		{outputBufferPassage, "", "John 3:16\n", "John 3:16\n"},
+		{outputBufferFull, "", "", "John 3:16\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			out := setOutput(t, tt.mode)
+			stdout.Write([]byte("John 3:16\n"))
+			if got := out.String(); got != tt.afterWrite {
+				t.Errorf("after a write, output = %q, want %q", got, tt.afterWrite)
+			}
+			passageDone()
+			if got := out.String(); got != tt.afterPassage {
+				t.Errorf("after passageDone, output = %q, want %q", got, tt.afterPassage)
+			}
+			flushOutput()
+			if got := out.String(); got != tt.afterFlushing {
+				t.Errorf("after flushOutput, output = %q, want %q", got, tt.afterFlushing)
+			}
+		})
+	}
+}
+
+func TestFlushOutputPending(t *testing.T) {
+	out := setOutput(t, outputBufferFull)
+	rawOutput = &pendingOutput{}
+	rawOutput.Write([]byte("{}\n"))
+	stdout.Write([]byte("John 3:16\n"))
+	flushOutput()
+	if got, want := out.String(), "John 3:16\n{}\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	writePendingOutput()
+	if got, want := out.String(), "John 3:16\n{}\n"; got != want {
+		t.Errorf("after writing pending output again, output = %q, want %q", got, want)
+	}
+}
+
+func TestNewlineTrimmer(t *testing.T) {
+	tests := []struct {
+		writes []string
+		want   string
+	}{
+		{nil, ""},
+		{[]string{"John 3:16\n"}, "John 3:16"},
+		{[]string{"a\n\n", "b\n"}, "a\n\nb"},
+		{[]string{"a", "\n", "\n", "b"}, "a\n\nb"},
+		{[]string{"\n\n"}, ""},
+		{[]string{"\n", "a\n"}, "\na"},
+		{[]string{"a\nb\n\n\n"}, "a\nb"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		w := &newlineTrimmer{w: &out}
+		for _, s := range tt.writes {
+			if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+				t.Errorf("Write(%q) = %d, %v, want %d, nil", s, n, err, len(s))
+			}
+		}
+		if got := out.String(); got != tt.want {
+			t.Errorf("writes %q gave %q, want %q", strings.Join(tt.writes, "|"), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// A verse pack is a portable collection of bookmarks, for sharing curated
+// verses with others:
+//
+//	{
+//	  "format": "bible-cli-verse-pack",
+//	  "version": 1,
+//	  "name": "Comfort",
+//	  "verses": [{"reference": "Psalm 23", "note": "…", "topics": ["comfort"]}]
+//	}
+const (
+	packFormat  = "bible-cli-verse-pack"
+	packVersion = 1
+)
+
+type versePack struct {
+	Format  string     `json:"format"`
+	Version int        `json:"version"`
+	Name    string     `json:"name,omitempty"`
+	Verses  []Bookmark `json:"verses"`
+}
+
+// parsePack decodes and validates a verse pack.
+func parsePack(data []byte) (versePack, error) {
+	var pack versePack
+	if err := json.Unmarshal(data, &pack); err != nil {
+		return pack, fmt.Errorf("not a verse pack: %w", err)
+	}
+	if pack.Format != packFormat {
+		return pack, fmt.Errorf("not a verse pack: format is %q, want %q", pack.Format, packFormat)
+	}
+	if pack.Version < 1 || pack.Version > packVersion {
+		return pack, fmt.Errorf("unsupported verse pack version %d (want %d)", pack.Version, packVersion)
+	}
+	for i, b := range pack.Verses {
+		if err := checkBookmark(b); err != nil {
+			return pack, fmt.Errorf("verse %d: %w", i+1, err)
+		}
+	}
+	return pack, nil
+}
+
+// runPack implements "pack export [FILE]", which writes the bookmarks as a
+// verse pack, and "pack import FILE", which merges a verse pack into them.
+// FILE "-" is stdin or stdout. It returns the exit code.
+func runPack(args []string, opts DisplayOptions) int {
+	flags := flag.NewFlagSet("pack", flag.ContinueOnError)
+	name := flags.String("name", "", "Name of the exported pack")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+	switch {
+	case len(args) >= 1 && args[0] == "export" && len(args) <= 2:
+	case len(args) == 2 && args[0] == "import":
+	default:
+		fmt.Fprintln(os.Stderr, "Error: want 'pack export [FILE]' or 'pack import FILE'")
+		return 2
+	}
+
+	state, err := loadState()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if args[0] == "export" {
+		pack := versePack{Format: packFormat, Version: packVersion, Name: *name, Verses: state.Bookmarks}
+		if pack.Verses == nil {
+			pack.Verses = []Bookmark{}
+		}
+		if len(args) == 1 || args[1] == "-" {
+			return printJSON(pack, opts.CompactJSON)
+		}
+		data, err := json.MarshalIndent(pack, "", "  ")
+		if err == nil {
+			err = os.WriteFile(args[1], append(data, '\n'), 0o644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: exporting pack: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	var data []byte
+	if args[1] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(args[1])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	pack, err := parsePack(bytes.TrimSpace(data))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", args[1], err)
+		return 1
+	}
+	added := 0
+	for _, b := range pack.Verses {
+		if state.addBookmark(b) {
+			added++
+		}
+	}
+	if err := saveState(state); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: saving bookmarks: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Imported %d new bookmarks, %d already bookmarked\n", added, len(pack.Verses)-added)
+	return 0
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePack(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		wantVerses int
+		wantErr    string
+	}{
+		{"valid", `{"format": "bible-cli-verse-pack", "version": 1, "name": "Comfort", "verses": [{"reference": "Psalm 23", "topics": ["comfort"]}, {"reference": "John 14:27"}]}`, 2, ""},
+		{"empty", `{"format": "bible-cli-verse-pack", "version": 1, "verses": []}`, 0, ""},
+		{"not JSON", `verses: Psalm 23`, 0, "not a verse pack"},
+		{"other format", `{"format": "something-else", "version": 1, "verses": []}`, 0, `format is "something-else"`},
+		{"no format", `{"version": 1, "verses": []}`, 0, "not a verse pack"},
+		{"newer version", `{"format": "bible-cli-verse-pack", "version": 2, "verses": []}`, 0, "unsupported verse pack version 2"},
+		{"no version", `{"format": "bible-cli-verse-pack", "verses": []}`, 0, "unsupported verse pack version 0"},
+		{"missing reference", `{"format": "bible-cli-verse-pack", "version": 1, "verses": [{"reference": "Psalm 23"}, {"note": "?"}]}`, 0, "verse 2: missing reference"},
+		{"invalid reference", `{"format": "bible-cli-verse-pack", "version": 1, "verses": [{"reference": "Hezekiah 1:1"}]}`, 0, "verse 1: invalid reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pack, err := parsePack([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parsePack error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePack error = %v", err)
+			}
+			if len(pack.Verses) != tt.wantVerses {
+				t.Errorf("parsePack gave %d verses, want %d", len(pack.Verses), tt.wantVerses)
+			}
+		})
+	}
+}
+
+func TestPackImportMerges(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	if err := saveState(State{Streak: 3, Bookmarks: []Bookmark{{Reference: "Psalm 23", Note: "mine"}}}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "pack.json")
+	pack := `{"format": "bible-cli-verse-pack", "version": 1, "verses": [
+		{"reference": "psalm 23", "note": "theirs", "topics": ["comfort"]},
+		{"reference": "John 14:27"},
+		{"reference": "john 14:27"}]}`
+	if err := os.WriteFile(path, []byte(pack), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var code int
+	out := captureOutput(t, func() { code = runPack([]string{"import", path}, DisplayOptions{}) })
+	if want := "Imported 1 new bookmarks, 2 already bookmarked\n"; code != 0 || out != want {
+		t.Errorf("pack import = %d, %q, want 0, %q", code, out, want)
+	}
+	state, err := loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Bookmark{{Reference: "Psalm 23", Note: "mine", Topics: []string{"comfort"}}, {Reference: "John 14:27"}}
+	if len(state.Bookmarks) != len(want) || !equalBookmarks(state.Bookmarks[0], want[0]) || !equalBookmarks(state.Bookmarks[1], want[1]) {
+		t.Errorf("bookmarks after import = %+v, want %+v", state.Bookmarks, want)
+	}
+	if state.Streak != 3 {
+		t.Errorf("streak after import = %d, want 3 as before", state.Streak)
+	}
+
+	// An invalid pack leaves the bookmarks alone
+	os.WriteFile(path, []byte(`{"format": "bible-cli-verse-pack", "version": 9, "verses": []}`), 0o644)
+	if code := runPack([]string{"import", path}, DisplayOptions{}); code != 1 {
+		t.Errorf("pack import of an invalid pack = %d, want 1", code)
+	}
+}
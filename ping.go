@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// pingReference is the passage requested by ping: the shortest verse keeps
+// the response small.
+const pingReference = "John 11:35"
+
+// runPing implements the ping command, checking the API token with a single
+// uncached request and reporting the latency. It returns the exit code.
+func runPing(apiKey string) int {
+	client := NewBibleClient(apiKey, FetchOptions{})
+
+	start := time.Now()
+	verse, err := client.FetchVerse(pingReference)
+	latency := time.Since(start)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FAIL: %v\n", err)
+		return 1
+	}
+	if len(verse.Passages) == 0 {
+		fmt.Fprintf(os.Stderr, "FAIL: no passage returned for %s\n", pingReference)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "OK %s (%v)\n", apiBaseURL, latency.Round(time.Millisecond))
+	return 0
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ANSI sequences for taking over the terminal while presenting.
+const (
+	ansiAltScreen   = "\x1b[?1049h\x1b[?25l" // Switch to the alternate screen, hide the cursor
+	ansiMainScreen  = "\x1b[?25h\x1b[?1049l" // Show the cursor, back to the normal screen
+	ansiClearScreen = "\x1b[2J"
+)
+
+// presentMaxWidth caps the width of a presented verse so lines stay short
+// enough to read from across a room.
+const presentMaxWidth = 80
+
+// runPresent implements the present command, showing one verse at a time
+// centered on the otherwise blank terminal. Each key press advances to the
+// next of references, or to another random verse from pool if there are
+// none; q, Esc or Ctrl-C ends the presentation. It returns the exit code.
+func runPresent(client *Dispatcher, references, pool []string, opts DisplayOptions) int {
+	in := int(os.Stdin.Fd())
+	if !term.IsTerminal(in) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Fprintln(os.Stderr, "Error: present needs a terminal")
+		return 1
+	}
+	// Projected text reads best without colors
+	opts.ColorReference = false
+
+	oldState, err := term.MakeRaw(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprint(os.Stdout, ansiAltScreen)
+	restore := func() {
+		fmt.Fprint(os.Stdout, ansiMainScreen)
+		term.Restore(in, oldState)
+	}
+
+	for i := 0; len(references) == 0 || i < len(references); i++ {
+		var verse *ESVResponse
+		if len(references) == 0 {
+			verse, err = client.GetRandomVerse(pool)
+		} else {
+			verse, err = client.FetchVerse(references[i])
+		}
+		if err != nil {
+			restore()
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		presentVerse(verse, opts)
+		if !waitForKey() {
+			break
+		}
+	}
+	restore()
+	return 0
+}
+
+// presentVerse clears the screen and draws the box of verse in the middle
+// of it.
+func presentVerse(verse *ESVResponse, opts DisplayOptions) {
+	termWidth, termHeight, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		termWidth, termHeight = 80, 24
+	}
+	width := min(termWidth-4, presentMaxWidth)
+	width = max(width, 20)
+
+	var b strings.Builder
+	fmt.Fprintln(&b, strings.Repeat("═", width))
+	if len(verse.Passages) == 0 {
+		fmt.Fprintln(&b, " No passage found")
+	}
+	for i, passage := range verse.Passages {
+		if i > 0 {
+			fmt.Fprintln(&b, strings.Repeat("┄", width))
+		}
+		printPassageSection(&b, passageHeader(verse, i, opts), passage, width, opts)
+	}
+	fmt.Fprint(&b, strings.Repeat("═", width))
+	lines := strings.Split(b.String(), "\n")
+
+	top := max((termHeight-len(lines))/2, 0)
+	left := max((termWidth-width)/2, 0)
+	fmt.Fprint(os.Stdout, ansiClearScreen)
+	for i, line := range lines {
+		// Raw mode doesn't translate newlines, so place every line
+		fmt.Fprintf(os.Stdout, "\x1b[%d;%dH%s", top+i+1, left+1, line)
+	}
+}
+
+// waitForKey blocks until a key is pressed on the raw terminal and reports
+// whether the presentation should go on.
+func waitForKey() bool {
+	buf := make([]byte, 8)
+	n, err := os.Stdin.Read(buf)
+	if err != nil || n == 0 {
+		return false
+	}
+	switch {
+	case buf[0] == 'q', buf[0] == 3: // Ctrl-C
+		return false
+	case buf[0] == 0x1b && n == 1: // Esc on its own, not an arrow key
+		return false
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package main
+
+import "errors"
+
+// Provider is a source of passages, such as the ESV API. The Dispatcher
+// picks a provider for each reference by its translation.
+type Provider interface {
+	// FetchVerse fetches the passages of reference. A reference matching
+	// nothing yields a response without passages rather than an error.
+	FetchVerse(reference string) (*ESVResponse, error)
+
+	// Search returns up to limit passages containing words.
+	Search(words string, limit int) ([]SearchResult, error)
+
+	// Random picks the reference of a random verse from pool, using intn
+	// for a number in [0, n) so that seeded picks can be repeated.
+	Random(pool []string, intn func(n int) int) (string, error)
+
+	// WaitForRefreshes blocks until background cache refreshes have
+	// finished.
+	WaitForRefreshes()
+}
+
+// SearchResult is a passage found by Provider.Search.
+type SearchResult struct {
+	Reference string `json:"reference"`
+	Content   string `json:"content"`
+}
+
+// pickFromPool implements Provider.Random for providers without random
+// verses of their own.
+func pickFromPool(pool []string, intn func(n int) int) (string, error) {
+	if len(pool) == 0 {
+		return "", errors.New("no verses to pick from")
+	}
+	return pool[intn(len(pool))], nil
+}
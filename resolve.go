@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// maxResolvedReferences bounds the references a referenceResolver keeps.
+const maxResolvedReferences = 1000
+
+// resolvedReferencesKey is the cache key the resolved references are
+// stored under.
+const resolvedReferencesKey = "resolved-references"
+
+// resolvedReference records the canonical reference the API resolved an
+// input to, e.g. "John 3:16" for "jn 3 16".
+type resolvedReference struct {
+	Input     string `json:"input"`
+	Reference string `json:"reference"`
+}
+
+// referenceResolver remembers the canonical reference of inputs that were
+// fetched before, so repeating sloppy input asks for the same passage as
+// the canonical reference and hits the same cache entry. Once full, the
+// oldest entries are forgotten first. A nil referenceResolver resolves
+// nothing.
+type referenceResolver struct {
+	mu      sync.Mutex
+	entries []resolvedReference // Oldest first
+	byInput map[string]string
+	cache   responseCache // Where entries persist, e.g. across runs
+}
+
+func newReferenceResolver(cache responseCache) *referenceResolver {
+	r := &referenceResolver{byInput: make(map[string]string), cache: cache}
+	if entry, found := cache.Get(resolvedReferencesKey); found {
+		if err := json.Unmarshal(entry.Body, &r.entries); err != nil {
+			debugLog.Printf("ignoring resolved references: %v", err)
+			r.entries = nil
+		}
+	}
+	for _, entry := range r.entries {
+		r.byInput[entry.Input] = entry.Reference
+	}
+	return r
+}
+
+// inputKey normalizes input so differences in case and spacing don't count.
+func inputKey(input string) string {
+	return strings.ToLower(strings.Join(strings.Fields(input), " "))
+}
+
+// Resolve returns the canonical reference remembered for input.
+func (r *referenceResolver) Resolve(input string) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reference, ok := r.byInput[inputKey(input)]
+	return reference, ok
+}
+
+// Remember records that input resolved to reference. The entries are only
+// saved when input is new.
+func (r *referenceResolver) Remember(input, reference string) {
+	if r == nil {
+		return
+	}
+	key := inputKey(input)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.byInput[key]; ok || key == inputKey(reference) {
+		return
+	}
+	r.byInput[key] = reference
+	r.entries = append(r.entries, resolvedReference{key, reference})
+	if len(r.entries) > maxResolvedReferences {
+		delete(r.byInput, r.entries[0].Input)
+		r.entries = r.entries[1:]
+	}
+	r.save()
+}
+
+// Reset forgets all resolved references.
+func (r *referenceResolver) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) == 0 {
+		return
+	}
+	r.entries = nil
+	clear(r.byInput)
+	r.save()
+}
+
+// save writes the entries to the cache. The caller holds r.mu.
+func (r *referenceResolver) save() {
+	data, err := json.Marshal(r.entries)
+	if err != nil {
+		debugLog.Printf("saving resolved references: %v", err)
+		return
+	}
+	r.cache.Put(resolvedReferencesKey, data)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// countingCache counts the writes to a memoryCache.
+type countingCache struct {
+	*memoryCache
+	puts int
+}
+
+func (c *countingCache) Put(key string, body []byte) {
+	c.puts++
+	c.memoryCache.Put(key, body)
+}
+
+func TestReferenceResolverSavesNewEntries(t *testing.T) {
+	cache := &countingCache{memoryCache: newMemoryCache(time.Hour)}
+	r := newReferenceResolver(cache)
+	steps := []struct {
+		input, reference string
+		wantPuts         int
+	}{
+		{"jn 3:16", "John 3:16", 1},
+		{"jn 3:16", "John 3:16", 1},
+		{"JN  3:16", "John 3:16", 1},  // The same input once normalized
+		{"John 3:16", "John 3:16", 1}, // Already canonical
+		{"rom 8:28", "Romans 8:28", 2},
+		{"jn 3:16", "John 3:16", 2},
+	}
+	for _, step := range steps {
+		r.Remember(step.input, step.reference)
+		if cache.puts != step.wantPuts {
+			t.Errorf("after Remember(%q, %q), %d saves, want %d", step.input, step.reference, cache.puts, step.wantPuts)
+		}
+	}
+
+	// A new resolver reads the saved entries back
+	if got, ok := newReferenceResolver(cache).Resolve("Jn 3:16"); got != "John 3:16" || !ok {
+		t.Errorf("Resolve(%q) = %q, %v, want %q, true", "Jn 3:16", got, ok, "John 3:16")
+	}
+
+	r.Reset()
+	r.Reset()
+	if cache.puts != 3 {
+		t.Errorf("after Reset twice, %d saves, want 3", cache.puts)
+	}
+	if _, ok := r.Resolve("jn 3:16"); ok {
+		t.Error("Resolve after Reset found an entry")
+	}
+}
+
+func TestReferenceResolverBound(t *testing.T) {
+	r := newReferenceResolver(nopCache{})
+	for i := range maxResolvedReferences + 1 {
+		r.Remember(fmt.Sprintf("ps %d", i), fmt.Sprintf("Psalm %d", i))
+	}
+	if _, ok := r.Resolve("ps 0"); ok {
+		t.Error("the oldest entry is still resolved past the bound")
+	}
+	if got, _ := r.Resolve("ps 1"); got != "Psalm 1" {
+		t.Errorf("Resolve(%q) = %q, want %q", "ps 1", got, "Psalm 1")
+	}
+}
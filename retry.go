@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// errIncompleteResponse is returned when the connection fails while reading
+// the response body.
+var errIncompleteResponse = errors.New("incomplete response from API")
+
+// apiError is a non-200 response from the API.
+type apiError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // From the Retry-After header, if any
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+func newAPIError(resp *http.Response, body []byte) *apiError {
+	err := &apiError{StatusCode: resp.StatusCode, Body: string(body)}
+	if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil {
+		err.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return err
+}
+
+// isRateLimited reports whether err is the API refusing a request because
+// of its rate limit.
+func isRateLimited(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests
+}
+
+// isRetryable reports whether a request that failed with err may succeed if
+// tried again: rate limiting, server errors, timeouts and responses cut off
+// mid-body.
+func isRetryable(err error) bool {
+	if errors.Is(err, errIncompleteResponse) {
+		return true
+	}
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isDNSError reports whether err is a failure to look up the API's host,
+// which is often transient, e.g. right after a laptop wakes from sleep.
+func isDNSError(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// Jitter strategies, which randomize the backoff delay so that many clients
+// failing at once don't all retry at the same moment.
+const (
+	jitterNone  = "none"  // Wait exactly the exponential delay
+	jitterFull  = "full"  // Wait anywhere from zero up to the delay
+	jitterEqual = "equal" // Wait at least half the delay, up to all of it
+)
+
+// retryPolicy retries failed requests with exponential backoff. The zero
+// value makes a single attempt.
+type retryPolicy struct {
+	Retries   int           // Attempts after the first
+	BaseDelay time.Duration // Delay before the first retry, doubled for each one after
+	MaxDelay  time.Duration // Cap on the delay between attempts
+	Jitter    string        // One of the jitter strategies; full if empty
+
+	// DNSDelay replaces BaseDelay after DNS failures, which usually clear up
+	// within a second. Zero only retries DNS lookups that timed out, with
+	// the usual delay.
+	DNSDelay time.Duration
+}
+
+var defaultRetryPolicy = retryPolicy{
+	Retries:   2,
+	BaseDelay: 500 * time.Millisecond,
+	MaxDelay:  8 * time.Second,
+	Jitter:    jitterFull,
+	DNSDelay:  250 * time.Millisecond,
+}
+
+// do calls attempt until it succeeds, fails with an error that isn't
+// retryable, or the retries are used up, returning the last error.
+func (p retryPolicy) do(attempt func() error) error {
+	for retry := 0; ; retry++ {
+		err := attempt()
+		if err == nil || retry >= p.Retries || !p.retryable(err) {
+			return err
+		}
+
+		delay := p.retryDelay(retry, err)
+		debugLog.Printf("retrying in %v: %v", delay, err)
+		time.Sleep(delay)
+	}
+}
+
+// retryDelay returns how long to wait before the given retry of a request
+// that failed with err. DNS failures wait on DNSDelay with equal jitter
+// rather than full, so the lookup always gets at least half of it to
+// recover. A longer Retry-After from the API wins.
+func (p retryPolicy) retryDelay(retry int, err error) time.Duration {
+	delay := p.delay(retry)
+	if isDNSError(err) && p.DNSDelay > 0 {
+		dns := p
+		dns.BaseDelay = p.DNSDelay
+		if dns.Jitter != jitterNone {
+			dns.Jitter = jitterEqual
+		}
+		delay = dns.delay(retry)
+	}
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > delay {
+		delay = apiErr.RetryAfter
+	}
+	return delay
+}
+
+// retryable reports whether the policy retries a request that failed with
+// err: if isRetryable says so, or after any DNS failure unless DNSDelay is
+// zero.
+func (p retryPolicy) retryable(err error) bool {
+	return isRetryable(err) || p.DNSDelay > 0 && isDNSError(err)
+}
+
+// delay returns how long to wait before the given retry, counting from 0:
+// the exponential delay, randomized by the policy's jitter strategy.
+func (p retryPolicy) delay(retry int) time.Duration {
+	delay := p.BaseDelay << retry
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+	if delay <= 1 {
+		return max(delay, 0)
+	}
+	switch p.Jitter {
+	case jitterNone:
+		return delay
+	case jitterEqual:
+		half := delay / 2
+		return half + time.Duration(rand.Int63n(int64(delay-half)))
+	default:
+		return time.Duration(rand.Int63n(int64(delay)))
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubTransport answers requests with the errors in failures, one per
+// request, then with body.
+type stubTransport struct {
+	failures []error
+	body     string
+	requests int
+}
+
+func (s *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.requests++
+	if s.requests <= len(s.failures) {
+		return nil, s.failures[s.requests-1]
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(s.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestFetchVerseRetriesDNSFailure(t *testing.T) {
+	lookupFailed := &net.DNSError{Err: "no such host", Name: "api.esv.org", IsNotFound: true}
+	tests := []struct {
+		name         string
+		failures     []error
+		retry        retryPolicy
+		wantErr      bool
+		wantRequests int
+	}{
+		{"lookup fails once", []error{lookupFailed}, retryPolicy{Retries: 2, DNSDelay: time.Millisecond, MaxDelay: time.Second}, false, 2},
+		{"lookup fails twice", []error{lookupFailed, lookupFailed}, retryPolicy{Retries: 2, DNSDelay: time.Millisecond, MaxDelay: time.Second}, false, 3},
+		{"retries used up", []error{lookupFailed, lookupFailed}, retryPolicy{Retries: 1, DNSDelay: time.Millisecond, MaxDelay: time.Second}, true, 2},
+		{"DNS retries off", []error{lookupFailed}, retryPolicy{Retries: 2, MaxDelay: time.Second}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &stubTransport{failures: tt.failures, body: `{"canonical": "John 3:16", "passages": ["For God so loved the world"]}`}
+			client := NewBibleClient("test-token", FetchOptions{Retry: tt.retry})
+			client.client.Transport = transport
+			verse, err := client.FetchVerse("John 3:16")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchVerse error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !isDNSError(err) {
+				t.Errorf("FetchVerse error = %v, want the DNS error", err)
+			}
+			if err == nil && len(verse.Passages) != 1 {
+				t.Errorf("FetchVerse passages = %q, want one", verse.Passages)
+			}
+			if transport.requests != tt.wantRequests {
+				t.Errorf("made %d requests, want %d", transport.requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestRetryDelayDNSFloor(t *testing.T) {
+	lookupFailed := fmt.Errorf("making request: %w", &net.DNSError{Err: "no such host", Name: "api.esv.org"})
+	for _, jitter := range []string{jitterFull, jitterEqual, jitterNone, ""} {
+		p := retryPolicy{Retries: 3, BaseDelay: 4 * time.Second, MaxDelay: 8 * time.Second, Jitter: jitter, DNSDelay: 200 * time.Millisecond}
+		for retry := range 3 {
+			dnsDelay := p.DNSDelay << retry
+			for range 100 {
+				if got := p.retryDelay(retry, lookupFailed); got < dnsDelay/2 || got > dnsDelay {
+					t.Fatalf("jitter %q: retryDelay(%d, DNS error) = %v, want %v to %v", jitter, retry, got, dnsDelay/2, dnsDelay)
+				}
+			}
+		}
+	}
+}
+
+func TestRetryDelayRetryAfter(t *testing.T) {
+	p := retryPolicy{Retries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second, Jitter: jitterNone}
+	tests := []struct {
+		err  error
+		want time.Duration
+	}{
+		{&apiError{StatusCode: http.StatusTooManyRequests, RetryAfter: 5 * time.Second}, 5 * time.Second},
+		{&apiError{StatusCode: http.StatusTooManyRequests}, time.Millisecond},
+		{&apiError{StatusCode: http.StatusServiceUnavailable, RetryAfter: time.Microsecond}, time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := p.retryDelay(0, tt.err); got != tt.want {
+			t.Errorf("retryDelay(0, %v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"rate limited", &apiError{StatusCode: http.StatusTooManyRequests}, true},
+		{"server error", &apiError{StatusCode: http.StatusBadGateway}, true},
+		{"bad request", &apiError{StatusCode: http.StatusBadRequest}, false},
+		{"incomplete body", fmt.Errorf("reading: %w", errIncompleteResponse), true},
+		{"DNS timeout", &net.DNSError{Err: "timeout", IsTimeout: true}, true},
+		{"unknown host", &net.DNSError{Err: "no such host", IsNotFound: true}, false},
+		{"other", errors.New("oops"), false},
+	}
+	for _, tt := range tests {
+		if got := isRetryable(tt.err); got != tt.want {
+			t.Errorf("isRetryable(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
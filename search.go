@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// searchResults is how many passages the search command shows.
+	searchResults = 20
+
+	// maxSearchPageSize is the most results the ESV API returns at once.
+	maxSearchPageSize = 100
+)
+
+// esvSearchResponse is the part of an ESV API search response that is used.
+type esvSearchResponse struct {
+	TotalResults int            `json:"total_results"`
+	Results      []SearchResult `json:"results"`
+}
+
+// Search returns up to limit passages containing words, from the first page
+// of the API's search results.
+func (bc *BibleClient) Search(words string, limit int) ([]SearchResult, error) {
+	params := url.Values{}
+	params.Add("q", words)
+	params.Add("page-size", strconv.Itoa(min(limit, maxSearchPageSize)))
+	var resp esvSearchResponse
+	if _, err := bc.fetchJSON(bc.searchURL, params, &resp); err != nil {
+		return nil, err
+	}
+	debugLog.Printf("search for %q found %d passages", words, resp.TotalResults)
+	if len(resp.Results) > limit {
+		resp.Results = resp.Results[:limit]
+	}
+	return resp.Results, nil
+}
+
+// Random picks from pool, as the ESV API has no random verses.
+func (bc *BibleClient) Random(pool []string, intn func(n int) int) (string, error) {
+	return pickFromPool(pool, intn)
+}
+
+// runSearch implements the search command, listing the passages containing
+// words. It returns the exit code.
+func runSearch(client *Dispatcher, words string, opts DisplayOptions) int {
+	results, err := client.Search(words, searchResults)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if opts.Format == formatJSON {
+		if results == nil {
+			results = []SearchResult{}
+		}
+		return printJSON(results, opts.CompactJSON)
+	}
+	if len(results) == 0 {
+		fmt.Fprintf(os.Stderr, "Nothing found for %q\n", words)
+		return 1
+	}
+	width := max(getTerminalWidth()-2, 20)
+	for i, result := range results {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		header := result.Reference
+		if opts.ColorReference {
+			header = styled(referenceColor(result.Reference), result.Reference)
+		}
+		fmt.Fprintln(stdout, header)
+		for _, line := range wrapText(strings.Join(strings.Fields(result.Content), " "), width) {
+			fmt.Fprintf(stdout, "  %s\n", line)
+		}
+	}
+	return 0
+}
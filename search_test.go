@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBibleClientSearch(t *testing.T) {
+	tests := []struct {
+		limit        int
+		wantPageSize string
+		wantResults  int
+	}{
+		{2, "2", 2},
+		{20, "20", 3},
+		{500, "100", 3},
+	}
+	for _, tt := range tests {
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/search/" {
+				t.Errorf("request for %s, want /search/", r.URL.Path)
+			}
+			query := r.URL.Query()
+			if got := query.Get("q"); got != "living water" {
+				t.Errorf("q = %q, want %q", got, "living water")
+			}
+			if got := query.Get("page-size"); got != tt.wantPageSize {
+				t.Errorf("page-size = %q, want %q", got, tt.wantPageSize)
+			}
+			fmt.Fprint(w, `{"page": 1, "total_results": 3, "total_pages": 1, "results": [
+				{"reference": "John 4:10", "content": "living water"},
+				{"reference": "John 4:11", "content": "that living water"},
+				{"reference": "John 7:38", "content": "rivers of living water"}]}`)
+		}, FetchOptions{})
+		results, err := client.Search("living water", tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != tt.wantResults || results[0].Reference != "John 4:10" {
+			t.Errorf("Search(limit %d) = %v, want %d results from John 4:10", tt.limit, results, tt.wantResults)
+		}
+	}
+}
+
+func TestRunSearch(t *testing.T) {
+	d, _ := newFakeDispatcher(Config{}, "")
+	var code int
+	got := captureOutput(t, func() { code = runSearch(d, "living water", DisplayOptions{}) })
+	if code != 0 {
+		t.Errorf("runSearch() = %d, want 0", code)
+	}
+	if want := "John 4:14\n  ESV: living water\n"; got != want {
+		t.Errorf("runSearch() output = %q, want %q", got, want)
+	}
+
+	got = captureOutput(t, func() { code = runSearch(d, "living water", DisplayOptions{Format: formatJSON, CompactJSON: true}) })
+	if want := `[{"reference":"John 4:14","content":"ESV: living water"}]`; code != 0 || strings.TrimSpace(got) != want {
+		t.Errorf("runSearch() JSON = %d, %q, want 0, %q", code, got, want)
+	}
+}
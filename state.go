@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// State is what bible-cli remembers between runs.
+type State struct {
+	LastRead string `json:"last_read,omitempty"` // Day of the last reading, as YYYY-MM-DD
+	Streak   int    `json:"streak,omitempty"`    // Consecutive days read, up to LastRead
+
+	LastShown string `json:"last_shown,omitempty"` // Day of the last -once-per-day output
+
+	Bookmarks []Bookmark `json:"bookmarks,omitempty"`
+}
+
+const dayLayout = "2006-01-02"
+
+// statePath returns the path of the state file, bible-cli/state.json in
+// $XDG_STATE_HOME or ~/.local/state.
+func statePath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "bible-cli", "state.json"), nil
+}
+
+// loadState reads the state file. A missing file yields the zero State.
+func loadState() (State, error) {
+	var state State
+	path, err := statePath()
+	if err != nil {
+		return state, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("reading state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("parsing state %s: %w", path, err)
+	}
+	return state, nil
+}
+
+func saveState(state State) error {
+	path, err := statePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0o644)
+}
+
+// writeFileAtomic writes data to the file at path through a temporary file
+// renamed over it, so a crash or a concurrent run never leaves it partly
+// written.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// recordReading extends the streak to the day of now: reading again on the
+// same day changes nothing, reading the day after the last reading adds a
+// day, and missing a day starts over.
+func (s *State) recordReading(now time.Time) {
+	today := now.Format(dayLayout)
+	switch s.LastRead {
+	case today:
+		return
+	case now.AddDate(0, 0, -1).Format(dayLayout):
+		s.Streak++
+	default:
+		s.Streak = 1
+	}
+	s.LastRead = today
+}
+
+// currentStreak returns the streak as of now, which is zero once a whole day
+// has passed without reading.
+func (s State) currentStreak(now time.Time) int {
+	if s.LastRead == now.Format(dayLayout) || s.LastRead == now.AddDate(0, 0, -1).Format(dayLayout) {
+		return s.Streak
+	}
+	return 0
+}
+
+// streakAfterReading returns the streak reading on the day of now gives,
+// without recording the reading. The streak is a nicety, so failures are
+// only logged.
+func streakAfterReading(now time.Time) int {
+	state, err := loadState()
+	if err != nil {
+		debugLog.Printf("loading state: %v", err)
+		return 0
+	}
+	state.recordReading(now)
+	return state.Streak
+}
+
+// shownToday reports whether -once-per-day already produced output on day.
+// An unreadable state file counts as not shown.
+func shownToday(day time.Time) bool {
+	state, err := loadState()
+	if err != nil {
+		debugLog.Printf("loading state: %v", err)
+		return false
+	}
+	return state.LastShown == day.Format(dayLayout)
+}
+
+// recordOutput notes in the state file that the user read today, if read,
+// and that -once-per-day produced output on day, if shown. The file is
+// loaded and saved once for both. Failures are only logged, at worst
+// showing a verse twice.
+func recordOutput(read, shown bool, day time.Time) {
+	if !read && !shown {
+		return
+	}
+	state, err := loadState()
+	if err != nil {
+		debugLog.Printf("loading state: %v", err)
+		return
+	}
+	if read {
+		state.recordReading(time.Now())
+	}
+	if shown {
+		state.LastShown = day.Format(dayLayout)
+	}
+	if err := saveState(state); err != nil {
+		debugLog.Printf("saving state: %v", err)
+	}
+}
+
+// streakText describes a streak of days, e.g. "🔥 3 day streak". Plain
+// output leaves out the emoji.
+func streakText(days int, opts DisplayOptions) string {
+	text := fmt.Sprintf(msg(msgDayStreak), days)
+	if opts.Format == formatPlain {
+		return text
+	}
+	return "🔥 " + text
+}
+
+// runStreak implements the streak command. It returns the exit code.
+func runStreak(opts DisplayOptions) int {
+	state, err := loadState()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	days := state.currentStreak(time.Now())
+
+	if opts.Format == formatJSON {
+		return printJSON(struct {
+			Streak   int    `json:"streak"`
+			LastRead string `json:"last_read,omitempty"`
+		}{days, state.LastRead}, opts.CompactJSON)
+	}
+	fmt.Fprintln(stdout, streakText(days, opts))
+	return 0
+}
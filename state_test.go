@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStateRecordReading(t *testing.T) {
+	now := time.Date(2024, 3, 10, 8, 0, 0, 0, time.Local)
+	tests := []struct {
+		name       string
+		state      State
+		wantStreak int
+	}{
+		{"first reading", State{}, 1},
+		{"same day", State{LastRead: "2024-03-10", Streak: 4}, 4},
+		{"next day", State{LastRead: "2024-03-09", Streak: 4}, 5},
+		{"missed a day", State{LastRead: "2024-03-08", Streak: 4}, 1},
+	}
+	for _, tt := range tests {
+		state := tt.state
+		state.recordReading(now)
+		if state.Streak != tt.wantStreak || state.LastRead != "2024-03-10" {
+			t.Errorf("%s: recordReading gave streak %d, last read %q, want %d, %q", tt.name, state.Streak, state.LastRead, tt.wantStreak, "2024-03-10")
+		}
+	}
+}
+
+func TestRecordOutput(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	day := time.Date(2024, 3, 10, 8, 0, 0, 0, time.Local)
+
+	if shownToday(day) {
+		t.Fatal("shownToday before any output = true, want false")
+	}
+	if got := streakAfterReading(time.Now()); got != 1 {
+		t.Errorf("streakAfterReading with no state = %d, want 1", got)
+	}
+
+	recordOutput(true, true, day)
+	state, err := loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Streak != 1 || state.LastRead != time.Now().Format(dayLayout) {
+		t.Errorf("after recordOutput, streak %d, last read %q, want 1, today", state.Streak, state.LastRead)
+	}
+	if !shownToday(day) {
+		t.Error("shownToday of the recorded day = false, want true")
+	}
+	if shownToday(day.AddDate(0, 0, 1)) {
+		t.Error("shownToday of the next day = true, want false")
+	}
+
+	// Output without a reading keeps the streak
+	recordOutput(false, true, day.AddDate(0, 0, 1))
+	if state, _ := loadState(); state.Streak != 1 || state.LastShown != "2024-03-11" {
+		t.Errorf("after recordOutput without a reading, streak %d, last shown %q, want 1, %q", state.Streak, state.LastShown, "2024-03-11")
+	}
+}
+
+func TestSaveStateAtomic(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	for i := range 3 {
+		if err := saveState(State{Streak: i + 1, Bookmarks: []Bookmark{{Reference: "John 3:16"}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	state, err := loadState()
+	if err != nil || state.Streak != 3 || len(state.Bookmarks) != 1 {
+		t.Errorf("loadState = %+v, %v, want streak 3 and the bookmark", state, err)
+	}
+	path, _ := statePath()
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "state.json" {
+		t.Errorf("state directory holds %v, want only state.json", entries)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o644 {
+		t.Errorf("state file mode = %v, %v, want 0644", info.Mode().Perm(), err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ANSI escape sequences for text styles.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiItalic = "\x1b[3m"
+)
+
+// referencePalette holds the colors referenceColor picks from: the bright
+// ANSI colors, skipping black and white which vanish on some backgrounds.
+var referencePalette = []string{
+	"\x1b[1;91m", // Red
+	"\x1b[1;92m", // Green
+	"\x1b[1;93m", // Yellow
+	"\x1b[1;94m", // Blue
+	"\x1b[1;95m", // Magenta
+	"\x1b[1;96m", // Cyan
+}
+
+// referenceColor returns the color for a reference, derived from its book
+// and chapter so every verse of a chapter gets the same color on every run.
+func referenceColor(reference string) string {
+	h := fnv.New32a()
+	if key, ok := parseReference(reference); ok {
+		fmt.Fprintf(h, "%d:%d", key.Book, key.Chapter)
+	} else {
+		h.Write([]byte(normalizeReference(reference)))
+	}
+	return referencePalette[h.Sum32()%uint32(len(referencePalette))]
+}
+
+// stylesEnabled reports whether output may contain ANSI styles: stdout must
+// be a terminal and NO_COLOR (https://no-color.org) must be unset.
+func stylesEnabled() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// styled wraps text in the given ANSI style when styles are enabled.
+func styled(style, text string) string {
+	if !stylesEnabled() {
+		return text
+	}
+	return style + text + ansiReset
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// maxSuggestions bounds the "Did you mean" list.
+const maxSuggestions = 3
+
+// suggestReferences returns references the user may have meant by query:
+// the API's suggestions if it gave any, otherwise the query with its book
+// name replaced by the closest known book names.
+func suggestReferences(verse *ESVResponse, query string) []string {
+	if verse != nil && len(verse.Suggestions) > 0 {
+		return verse.Suggestions
+	}
+
+	name, location, ok := splitReference(query)
+	if !ok {
+		return nil
+	}
+	var suggestions []string
+	for _, book := range fuzzyBookMatches(name) {
+		suggestion := bibleBooks[book].Name
+		if location != "" {
+			suggestion += " " + location
+		}
+		if normalizeReference(suggestion) == normalizeReference(query) {
+			continue // The book was right; the chapter or verse wasn't
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions
+}
+
+// fuzzyBookMatches returns the positions of up to maxSuggestions books
+// whose name or an abbreviation is within a few typos of name, closest
+// first. Equally close books come in canonical order, so the result doesn't
+// depend on the iteration order of bookIndex.
+func fuzzyBookMatches(name string) []int {
+	key := bookKey(name)
+	maxDistance := max(1, len(key)/3)
+
+	best := make(map[int]int) // Book position to smallest distance
+	for candidate, book := range bookIndex {
+		d := editDistance(key, candidate)
+		if d > maxDistance {
+			continue
+		}
+		if prev, ok := best[book]; !ok || d < prev {
+			best[book] = d
+		}
+	}
+
+	var books []int
+	for book := range best {
+		books = append(books, book)
+	}
+	sort.Slice(books, func(i, j int) bool {
+		if best[books[i]] != best[books[j]] {
+			return best[books[i]] < best[books[j]]
+		}
+		return books[i] < books[j]
+	})
+	if len(books) > maxSuggestions {
+		books = books[:maxSuggestions]
+	}
+	return books
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// chooseSuggestion shows the suggestions for a reference that wasn't found
+// and, when the user can answer on a terminal, lets them pick one. It
+// returns the chosen reference, or "" if none was picked.
+func chooseSuggestion(query string, suggestions []string, opts DisplayOptions) string {
+	interactive := term.IsTerminal(int(os.Stdin.Fd())) &&
+		(opts.Format == formatBox || opts.Format == formatPlain)
+	if !interactive {
+		fmt.Fprintf(os.Stderr, "No passage found for %q. Did you mean: %s?\n", query, strings.Join(suggestions, ", "))
+		return ""
+	}
+
+	fmt.Fprintf(stdout, "No passage found for %q. Did you mean:\n", query)
+	for i, suggestion := range suggestions {
+		fmt.Fprintf(stdout, "  %d) %s\n", i+1, suggestion)
+	}
+	fmt.Fprint(stdout, "Pick a number (Enter to cancel): ")
+	flushOutput()
+
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	n, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || n < 1 || n > len(suggestions) {
+		return ""
+	}
+	return suggestions[n-1]
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFuzzyBookMatches(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Jon", []string{"Jonah", "Joshua", "Job"}},
+		{"Jhn", []string{"John", "Jonah", "1 John"}},
+		{"Jud", []string{"Jude", "Judges"}},
+		{"Mat", []string{"Matthew", "Malachi", "Mark"}},
+		{"Jn", []string{"John", "Genesis", "Judges"}},
+		{"Titas", []string{"Titus"}},
+		{"Xyzzy", nil},
+	}
+	for _, tt := range tests {
+		// bookIndex is a map, so repeat to catch order that depends on it
+		for range 20 {
+			var got []string
+			for _, book := range fuzzyBookMatches(tt.name) {
+				got = append(got, bibleBooks[book].Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("fuzzyBookMatches(%q) = %q, want %q", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestSuggestReferences(t *testing.T) {
+	tests := []struct {
+		verse *ESVResponse
+		query string
+		want  []string
+	}{
+		{nil, "Jon 3:16", []string{"Joshua 3:16", "Job 3:16"}},
+		{nil, "Titas 3:5", []string{"Titus 3:5"}},
+		{nil, "Jhn", []string{"Jonah", "1 John"}}, // John is what was asked for
+		{&ESVResponse{Suggestions: []string{"John 3:16"}}, "Jon 3:16", []string{"John 3:16"}},
+		{nil, "Xyzzy 1:1", nil},
+	}
+	for _, tt := range tests {
+		if got := suggestReferences(tt.verse, tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("suggestReferences(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"john", "john", 0},
+		{"jon", "john", 1},
+		{"titas", "titus", 1},
+		{"", "job", 3},
+		{"mark", "mat", 2},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// topicCount is one line of the topics command.
+type topicCount struct {
+	Topic  string `json:"topic"`
+	Verses int    `json:"verses"`
+}
+
+// runTopics implements the topics command, listing the topic tags of the
+// built-in verses with the number of verses tagged with each. It returns the
+// exit code.
+func runTopics(opts DisplayOptions) int {
+	var topics []topicCount
+	for topic, references := range verseTopics {
+		topics = append(topics, topicCount{topic, len(references)})
+	}
+	slices.SortFunc(topics, func(a, b topicCount) int {
+		return cmp.Compare(a.Topic, b.Topic)
+	})
+
+	if opts.Format == formatJSON {
+		return printJSON(topics, opts.CompactJSON)
+	}
+	fmt.Fprintf(stdout, "%-12s %6s\n", "Topic", "Verses")
+	for _, t := range topics {
+		fmt.Fprintf(stdout, "%-12s %6d\n", t.Topic, t.Verses)
+	}
+	return 0
+}
+
+// errNoBuiltInVerses is returned by versePool for builds without verses.json.
+var errNoBuiltInVerses = errors.New("this build has no built-in verses to pick from; give a reference instead, e.g. John 3:16")
+
+// versePool returns the built-in verses random verses are picked from: those
+// of the books config allows, narrowed to one testament (testamentOld or
+// testamentNew) and one topic when these aren't empty.
+func versePool(testament, topic string, config Config) ([]string, error) {
+	if len(bibleVerses) == 0 {
+		return nil, errNoBuiltInVerses
+	}
+	pool := bibleVerses
+	if topic != "" {
+		var ok bool
+		if pool, ok = verseTopics[strings.ToLower(topic)]; !ok {
+			return nil, fmt.Errorf("unknown topic %q (run the topics command for the list)", topic)
+		}
+	}
+	pool = slices.DeleteFunc(slices.Clone(pool), func(reference string) bool {
+		key, ok := parseReference(reference)
+		return !ok || testament != "" && bookTestament(key.Book) != testament || !config.allowsBook(key.Book)
+	})
+	if len(pool) == 0 {
+		return nil, errors.New("no built-in verses match " + poolDescription(testament, topic, config))
+	}
+	return pool, nil
+}
+
+// poolDescription describes the filters of versePool for messages.
+func poolDescription(testament, topic string, config Config) string {
+	var filters []string
+	if testament != "" {
+		filters = append(filters, "-"+strings.ToLower(testament))
+	}
+	if topic != "" {
+		filters = append(filters, "-topic "+topic)
+	}
+	if len(config.Books) > 0 {
+		filters = append(filters, "the books allowed in the config file")
+	}
+	return strings.Join(filters, " and ")
+}
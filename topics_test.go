@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVersePoolWithoutVerses(t *testing.T) {
+	savedVerses, savedTopics := bibleVerses, verseTopics
+	t.Cleanup(func() { bibleVerses, verseTopics = savedVerses, savedTopics })
+
+	data, err := parseVerses(nil)
+	if err != nil {
+		t.Fatalf("parseVerses of an empty file: %v", err)
+	}
+	bibleVerses, verseTopics = data.Verses, data.Topics
+	for _, topic := range []string{"", "love"} {
+		if _, err := versePool("", topic, Config{}); !errors.Is(err, errNoBuiltInVerses) {
+			t.Errorf("versePool(topic %q) error = %v, want %v", topic, err, errNoBuiltInVerses)
+		}
+	}
+}
+
+func TestVersePool(t *testing.T) {
+	tests := []struct {
+		name      string
+		testament string
+		topic     string
+		config    Config
+		wantErr   bool
+	}{
+		{name: "all"},
+		{name: "testament", testament: testamentNew},
+		{name: "topic", topic: "love"},
+		{name: "unknown topic", topic: "nope", wantErr: true},
+		{name: "books", config: Config{Books: []string{"Psalms"}}},
+		{name: "nothing left", testament: testamentNew, config: Config{Books: []string{"OT"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := versePool(tt.testament, tt.topic, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("versePool error = %v, want error %v", err, tt.wantErr)
+			}
+			for _, reference := range pool {
+				key, ok := parseReference(reference)
+				if !ok || tt.testament != "" && bookTestament(key.Book) != tt.testament || !tt.config.allowsBook(key.Book) {
+					t.Errorf("pool has %q, which the filters exclude", reference)
+				}
+			}
+		})
+	}
+}
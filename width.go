@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/width"
+)
+
+// Treatments of East Asian ambiguous-width characters, such as "§" or
+// Greek letters, which terminals draw one or two columns wide depending on
+// their font and locale.
+const (
+	ambiguousNarrow = "narrow"
+	ambiguousWide   = "wide"
+)
+
+// ambiguousWidth is ambiguousNarrow or ambiguousWide, from -ambiguous-width.
+var ambiguousWidth = ambiguousNarrow
+
+// runeWidth returns the number of terminal columns r takes up.
+func runeWidth(r rune) int {
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) || unicode.IsControl(r) {
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	case width.EastAsianAmbiguous:
+		if ambiguousWidth == ambiguousWide {
+			return 2
+		}
+	}
+	return 1
+}
+
+// displayWidth returns the number of terminal columns s takes up.
+func displayWidth(s string) int {
+	total := 0
+	for _, r := range s {
+		total += runeWidth(r)
+	}
+	return total
+}
+
+// wrapToken is a unit of text that line wrapping keeps together: a word, or
+// a single wide character, as text in Chinese or Japanese has no spaces to
+// break at.
+type wrapToken struct {
+	text  string
+	width int
+	space bool // Preceded by a space in the text
+}
+
+// splitTokens splits text into the tokens it can be wrapped between.
+func splitTokens(text string) []wrapToken {
+	var tokens []wrapToken
+	for i, field := range strings.Fields(text) {
+		space := i > 0
+		start := 0
+		for pos, r := range field {
+			w := runeWidth(r)
+			if w < 2 {
+				continue
+			}
+			if pos > start {
+				tokens = append(tokens, wrapToken{field[start:pos], displayWidth(field[start:pos]), space})
+				space = false
+			}
+			end := pos + utf8.RuneLen(r)
+			tokens = append(tokens, wrapToken{field[pos:end], w, space})
+			space = false
+			start = end
+		}
+		if start < len(field) {
+			tokens = append(tokens, wrapToken{field[start:], displayWidth(field[start:]), space})
+		}
+	}
+	return tokens
+}
+
+// wrapTokens fills lines with tokens, as many as fit in the width widthFor
+// returns for each line. A token wider than its line gets a line of its own.
+func wrapTokens(tokens []wrapToken, widthFor func(line int) int) []string {
+	var result []string
+	var line strings.Builder
+	lineWidth := 0
+	for _, token := range tokens {
+		gap := 0
+		if token.space && lineWidth > 0 {
+			gap = 1
+		}
+		if lineWidth > 0 && lineWidth+gap+token.width > widthFor(len(result)) {
+			result = append(result, line.String())
+			line.Reset()
+			lineWidth, gap = 0, 0
+		}
+		if gap > 0 {
+			line.WriteByte(' ')
+		}
+		line.WriteString(token.text)
+		lineWidth += gap + token.width
+	}
+	if line.Len() > 0 {
+		result = append(result, line.String())
+	}
+	return result
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		text      string
+		ambiguous string
+		want      int
+	}{
+		{"John", ambiguousNarrow, 4},
+		{"約翰福音", ambiguousNarrow, 8},
+		{"ＡＢＣ", ambiguousNarrow, 6},  // Full-width Latin
+		{"요한복음", ambiguousNarrow, 8}, // Hangul
+		{"é", ambiguousNarrow, 1},   // Combining accent
+		{"§ α", ambiguousNarrow, 3},
+		{"§ α", ambiguousWide, 5},
+		{"約翰 3:16", ambiguousWide, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text+"/"+tt.ambiguous, func(t *testing.T) {
+			saved := ambiguousWidth
+			ambiguousWidth = tt.ambiguous
+			t.Cleanup(func() { ambiguousWidth = saved })
+			if got := displayWidth(tt.text); got != tt.want {
+				t.Errorf("displayWidth(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapTextWide(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxWidth int
+		want     []string
+	}{
+		{"fits", "神爱世人", 8, []string{"神爱世人"}},
+		{"breaks between wide characters", "神爱世人甚至将他的独生子赐给他们", 10, []string{"神爱世人甚", "至将他的独", "生子赐给他", "们"}},
+		{"full-width words", "ＡＢ ＣＤ ＥＦ", 9, []string{"ＡＢ ＣＤ", "ＥＦ"}},
+		{"mixed with Latin", "John 約翰福音 3:16", 10, []string{"John 約翰", "福音 3:16"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapText(tt.text, tt.maxWidth)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("wrapText(%q, %d) = %q, want %q", tt.text, tt.maxWidth, got, tt.want)
+			}
+			for _, line := range got {
+				if w := displayWidth(line); w > tt.maxWidth {
+					t.Errorf("line %q is %d columns wide, more than %d", line, w, tt.maxWidth)
+				}
+			}
+		})
+	}
+}